@@ -1,16 +1,21 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 
 	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
 	"github.com/gbengafagbola/knowledge-extractor/internal/llm"
+	"github.com/gbengafagbola/knowledge-extractor/internal/redact"
 	"github.com/gbengafagbola/knowledge-extractor/internal/server"
 
 	"github.com/joho/godotenv"
@@ -62,33 +67,200 @@ func main() {
 
 	defer db.Close()
 
-	// Create table if it doesn't exist (works for both PostgreSQL and SQLite)
-	if err := createTableIfNotExists(db, driver); err != nil {
-		log.Fatal("failed to create table:", err)
-	}
-
 	// LLM STRATEGY: Interface-based dependency injection with resilience
 	// Demonstrates several design patterns:
 	// 1. Strategy Pattern: Different LLM implementations
 	// 2. Decorator Pattern: ResilientClient wraps OpenAI with fallback
 	// 3. Dependency Inversion: Server depends on interface, not concrete types
 	var llmClient llm.LLM
+	var embedder llm.Embedder
 	if os.Getenv("USE_MOCK_LLM") == "true" || os.Getenv("OPENAI_API_KEY") == "" {
 		llmClient = llm.NewMockClient()
+		embedder = llm.NewHashingEmbedder(0)
 		fmt.Println("Using Mock LLM Client")
 	} else {
 		openaiClient := llm.NewOpenAIClient()
 		// ResilientClient implements circuit breaker pattern
 		llmClient = llm.NewResilientClient(openaiClient, llm.NewMockClient())
+		embedder = llm.NewOpenAIEmbedder()
 		fmt.Println("Using OpenAI LLM Client (with automatic mock fallback)")
 	}
+	// EMBEDDER=hashing keeps semantic search offline even when OpenAI is configured
+	if os.Getenv("EMBEDDER") == "hashing" {
+		embedder = llm.NewHashingEmbedder(0)
+	}
+	fmt.Println("Using embedding model " + embedder.Model())
 
 	// Create server
 	s := server.New(db, llmClient, driver)
+	s.Embedder = embedder
+
+	// PII REDACTION: off unless REDACT_POLICY is set
+	redactCfg, redactOn, err := redact.ConfigFromEnv()
+	if err != nil {
+		log.Fatal("invalid redaction config:", err)
+	}
+	if redactOn {
+		s.Redactor, err = redact.New(redactCfg)
+		if err != nil {
+			log.Fatal("invalid redaction config:", err)
+		}
+		fmt.Println("Redacting personal data before analysis")
+	}
+	s.AdminToken = os.Getenv("ADMIN_TOKEN")
+	if s.AdminToken == "" {
+		fmt.Println("ADMIN_TOKEN not set: editing and deleting analyses is disabled")
+	}
+
+	// KEYWORD SCORING: tfidf (default) or bm25 against the stored corpus
+	weighting, ok := analyzer.ParseWeighting(os.Getenv("KEYWORD_WEIGHTING"))
+	if !ok {
+		log.Fatal("invalid KEYWORD_WEIGHTING: ", os.Getenv("KEYWORD_WEIGHTING"))
+	}
+	s.KeywordWeighting = weighting
+
+	// LOCAL KEYWORDS: frequency (default), rake or textrank
+	extractor, ok := analyzer.ParseKeyphraseMethod(os.Getenv("KEYWORD_EXTRACTOR"))
+	if !ok {
+		log.Fatal("invalid KEYWORD_EXTRACTOR: ", os.Getenv("KEYWORD_EXTRACTOR"))
+	}
+	s.KeywordExtractor = extractor
+
+	// KEYWORD FILTERS: stopword lists per language (KEYWORD_STOPWORDS_<lang>=path), extra
+	// stopwords, normalization (none, stem or lemma), minimum length and numbers
+	for _, env := range os.Environ() {
+		name, path, _ := strings.Cut(env, "=")
+		lang, ok := strings.CutPrefix(name, "KEYWORD_STOPWORDS_")
+		if !ok {
+			continue
+		}
+		code, ok := analyzer.NormalizeLanguage(lang)
+		if !ok {
+			log.Fatal("invalid "+name+": unknown language ", lang)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatal("invalid "+name+": ", err)
+		}
+		words, err := analyzer.LoadStopwords(f)
+		f.Close()
+		if err != nil {
+			log.Fatal("invalid "+name+": ", err)
+		}
+		if s.KeywordOptions.Stopwords == nil {
+			s.KeywordOptions.Stopwords = make(map[string]map[string]bool)
+		}
+		s.KeywordOptions.Stopwords[code] = words
+	}
+	if v := os.Getenv("KEYWORD_EXTRA_STOPWORDS"); v != "" {
+		for _, w := range strings.Split(v, ",") {
+			if w = strings.TrimSpace(w); w != "" {
+				s.KeywordOptions.ExtraStopwords = append(s.KeywordOptions.ExtraStopwords, w)
+			}
+		}
+	}
+	normalization, ok := analyzer.ParseNormalization(os.Getenv("KEYWORD_NORMALIZATION"))
+	if !ok {
+		log.Fatal("invalid KEYWORD_NORMALIZATION: ", os.Getenv("KEYWORD_NORMALIZATION"))
+	}
+	s.KeywordOptions.Normalization = normalization
+	if v := os.Getenv("KEYWORD_MIN_LENGTH"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.Fatal("invalid KEYWORD_MIN_LENGTH: ", v)
+		}
+		s.KeywordOptions.MinLength = n
+	}
+	if v := os.Getenv("KEYWORD_KEEP_NUMBERS"); v != "" {
+		keep, err := strconv.ParseBool(v)
+		if err != nil {
+			log.Fatal("invalid KEYWORD_KEEP_NUMBERS: ", v)
+		}
+		s.KeywordOptions.KeepNumbers = keep
+	}
+
+	// LOCAL SUMMARIES: length in sentences and/or characters
+	for env, dst := range map[string]*int{
+		"SUMMARY_SENTENCES": &s.SummaryOptions.Sentences,
+		"SUMMARY_MAX_CHARS": &s.SummaryOptions.MaxChars,
+	} {
+		if v := os.Getenv(env); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				log.Fatal("invalid "+env+": ", v)
+			}
+			*dst = n
+		}
+	}
+
+	// ANALYSIS PIPELINE: per-field llm | local | merge, on top of the defaults
+	strategies, err := server.ParseFieldStrategies(os.Getenv("ANALYSIS_FIELDS"))
+	if err != nil {
+		log.Fatal("invalid ANALYSIS_FIELDS: ", err)
+	}
+	for field, strategy := range strategies {
+		s.FieldStrategies[field] = strategy
+	}
+
+	// ANALYSIS STAGES: a subset of the default pipeline (kept in its order) and per-stage error policies
+	if v := os.Getenv("ANALYSIS_STAGES"); v != "" {
+		var names []string
+		for _, name := range strings.Split(v, ",") {
+			names = append(names, strings.TrimSpace(name))
+		}
+		if s.Pipeline, err = s.Pipeline.Select(names); err != nil {
+			log.Fatal("invalid ANALYSIS_STAGES: ", err)
+		}
+	}
+	stagePolicies, err := server.ParseStagePolicies(os.Getenv("ANALYSIS_STAGE_POLICIES"))
+	if err != nil {
+		log.Fatal("invalid ANALYSIS_STAGE_POLICIES: ", err)
+	}
+	for stage, policy := range stagePolicies {
+		if err := s.Pipeline.SetPolicy(stage, policy); err != nil {
+			log.Fatal("invalid ANALYSIS_STAGE_POLICIES: ", err)
+		}
+	}
+	fmt.Println("Analysis pipeline: " + strings.Join(s.Pipeline.Stages(), " -> "))
+
+	// NEAR-DUPLICATES: store (default), link or reject copies at least DUPLICATE_THRESHOLD similar
+	policy, ok := server.ParseDuplicatePolicy(os.Getenv("DUPLICATE_POLICY"))
+	if !ok {
+		log.Fatal("invalid DUPLICATE_POLICY: ", os.Getenv("DUPLICATE_POLICY"))
+	}
+	s.DuplicatePolicy = policy
+	if v := os.Getenv("DUPLICATE_THRESHOLD"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f <= 0 || f > 1 {
+			log.Fatal("invalid DUPLICATE_THRESHOLD: ", v)
+		}
+		s.DuplicateThreshold = f
+	}
+
+	// Create tables if they don't exist (works for both PostgreSQL and SQLite)
+	if err := s.Migrate(); err != nil {
+		log.Fatal("failed to create tables:", err)
+	}
+	if err := s.LoadVectorIndex(context.Background()); err != nil {
+		log.Fatal("failed to load vector index:", err)
+	}
 
 	// Routes
 	http.HandleFunc("/analyze", s.AnalyzeHandler)
 	http.HandleFunc("/search", s.SearchHandler)
+	http.HandleFunc("/search/semantic", s.SemanticSearchHandler)
+	http.HandleFunc("/search/hybrid", s.HybridSearchHandler)
+	http.HandleFunc("/entities", s.EntitiesHandler)
+	http.HandleFunc("/ask", s.AskHandler)
+	http.HandleFunc("/reidentify", s.ReidentifyHandler)
+	http.HandleFunc("/keywords/recompute", s.RecomputeKeywordsHandler)
+	http.HandleFunc("/analyses", s.AnalysesHandler)
+	http.HandleFunc("/analyses/{id}", s.AnalysisHandler)
+	http.HandleFunc("GET /analyses/{id}/duplicates", s.DuplicatesHandler)
+	http.HandleFunc("/clusters", s.ClustersHandler)
+	http.HandleFunc("GET /clusters/{id}", s.ClusterHandler)
+	http.HandleFunc("/trends", s.TrendsHandler)
+	http.HandleFunc("/graph", s.GraphHandler)
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -98,45 +270,3 @@ func main() {
 	fmt.Println("Server running on port " + port)
 	log.Fatal(http.ListenAndServe(":"+port, nil))
 }
-
-// createTableIfNotExists creates the analyses table for both PostgreSQL and SQLite
-func createTableIfNotExists(db *sql.DB, driver string) error {
-	var createTableSQL string
-
-	if driver == "postgres" {
-		createTableSQL = `
-			CREATE TABLE IF NOT EXISTS analyses (
-				id TEXT PRIMARY KEY,
-				raw_text TEXT NOT NULL,
-				summary TEXT,
-				title TEXT,
-				topics TEXT[],
-				sentiment TEXT,
-				keywords TEXT[],
-				confidence NUMERIC,
-				created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
-			);`
-	} else {
-		// SQLite version - arrays stored as comma-separated strings
-		createTableSQL = `
-			CREATE TABLE IF NOT EXISTS analyses (
-				id TEXT PRIMARY KEY,
-				raw_text TEXT NOT NULL,
-				summary TEXT,
-				title TEXT,
-				topics TEXT,
-				sentiment TEXT,
-				keywords TEXT,
-				confidence REAL,
-				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-			);`
-	}
-
-	_, err := db.Exec(createTableSQL)
-	if err != nil {
-		return fmt.Errorf("failed to create table: %w", err)
-	}
-
-	fmt.Println("Database table 'analyses' created/verified successfully")
-	return nil
-}
@@ -0,0 +1,11 @@
+-- Requires pgvector (enabled by default on Supabase). Without it the server
+-- stores embeddings as BYTEA and serves semantic search from a local index.
+CREATE EXTENSION IF NOT EXISTS vector;
+
+CREATE TABLE IF NOT EXISTS analysis_embeddings (
+  analysis_id TEXT PRIMARY KEY REFERENCES analyses(id) ON DELETE CASCADE,
+  model TEXT NOT NULL,
+  embedding vector NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_analysis_embeddings_model ON analysis_embeddings (model);
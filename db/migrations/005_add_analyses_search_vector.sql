@@ -0,0 +1,10 @@
+-- Weighted full-text index used by hybrid search (title > summary > raw text).
+-- 'simple' applies no stemming or stopwords so it works for any language.
+ALTER TABLE analyses ADD COLUMN IF NOT EXISTS search_vector tsvector
+  GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(summary, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(raw_text, '')), 'C')
+  ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_analyses_search_vector ON analyses USING GIN (search_vector);
@@ -0,0 +1,6 @@
+-- Encrypted (AES-GCM) token maps for analyses redacted with the tokenize policy.
+-- Without REDACT_MAP_KEY the rows cannot be read, even with database access.
+CREATE TABLE IF NOT EXISTS redaction_maps (
+  analysis_id TEXT PRIMARY KEY REFERENCES analyses(id) ON DELETE CASCADE,
+  sealed_map BYTEA NOT NULL
+);
@@ -0,0 +1,3 @@
+-- Prompt-injection flag and score computed on ingest
+ALTER TABLE analyses ADD COLUMN IF NOT EXISTS injection_suspected BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE analyses ADD COLUMN IF NOT EXISTS injection_score NUMERIC;
@@ -0,0 +1,2 @@
+-- Grounding report: evidence spans for keywords/topics, summary overlap, hallucination flag
+ALTER TABLE analyses ADD COLUMN IF NOT EXISTS grounding JSONB;
@@ -0,0 +1,16 @@
+-- Document frequencies for corpus-aware (TF-IDF / BM25) keyword scoring, per language.
+-- Updated on every insert; POST /keywords/recompute rebuilds them from analyses.
+CREATE TABLE IF NOT EXISTS corpus_stats (
+  language TEXT PRIMARY KEY,
+  documents INTEGER NOT NULL DEFAULT 0,
+  tokens INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS term_stats (
+  language TEXT NOT NULL,
+  term TEXT NOT NULL,
+  documents INTEGER NOT NULL DEFAULT 0,
+  PRIMARY KEY (language, term)
+);
+
+ALTER TABLE analyses ADD COLUMN IF NOT EXISTS keyword_scores JSONB;
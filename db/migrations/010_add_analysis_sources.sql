@@ -0,0 +1,3 @@
+-- LLM provider that produced each analysis and the source (llm, local, merged) of each field
+ALTER TABLE analyses ADD COLUMN IF NOT EXISTS provider TEXT;
+ALTER TABLE analyses ADD COLUMN IF NOT EXISTS field_sources JSONB;
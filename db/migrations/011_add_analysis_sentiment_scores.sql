@@ -0,0 +1,3 @@
+-- Offline lexicon sentiment: compound score (-1..1) plus per-class scores and the LLM cross-check
+ALTER TABLE analyses ADD COLUMN IF NOT EXISTS sentiment_score NUMERIC;
+ALTER TABLE analyses ADD COLUMN IF NOT EXISTS sentiment_scores JSONB;
@@ -0,0 +1,8 @@
+-- Document statistics and readability; the scalar columns back the /search filters
+ALTER TABLE analyses ADD COLUMN IF NOT EXISTS text_stats JSONB;
+ALTER TABLE analyses ADD COLUMN IF NOT EXISTS word_count INTEGER;
+ALTER TABLE analyses ADD COLUMN IF NOT EXISTS sentence_count INTEGER;
+ALTER TABLE analyses ADD COLUMN IF NOT EXISTS reading_ease NUMERIC;
+ALTER TABLE analyses ADD COLUMN IF NOT EXISTS grade_level NUMERIC;
+
+CREATE INDEX IF NOT EXISTS idx_analyses_word_count ON analyses (word_count);
@@ -0,0 +1,12 @@
+-- Near-duplicate detection: MinHash signature per analysis, indexed by 16 LSH band hashes
+ALTER TABLE analyses ADD COLUMN IF NOT EXISTS minhash BYTEA;
+ALTER TABLE analyses ADD COLUMN IF NOT EXISTS duplicate_of TEXT;
+
+CREATE TABLE IF NOT EXISTS analysis_fingerprints (
+  analysis_id TEXT NOT NULL REFERENCES analyses(id) ON DELETE CASCADE,
+  band INTEGER NOT NULL,
+  value BIGINT NOT NULL,
+  PRIMARY KEY (band, value, analysis_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_analysis_fingerprints_analysis ON analysis_fingerprints (analysis_id);
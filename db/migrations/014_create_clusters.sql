@@ -0,0 +1,17 @@
+-- Corpus topic clusters from the last POST /clusters run, replaced wholesale on each run
+CREATE TABLE IF NOT EXISTS clusters (
+  id TEXT PRIMARY KEY,
+  label TEXT NOT NULL,
+  label_source TEXT NOT NULL,
+  terms JSONB,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS cluster_members (
+  cluster_id TEXT NOT NULL REFERENCES clusters(id) ON DELETE CASCADE,
+  analysis_id TEXT NOT NULL REFERENCES analyses(id) ON DELETE CASCADE,
+  similarity NUMERIC NOT NULL,
+  PRIMARY KEY (cluster_id, analysis_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_cluster_members_analysis ON cluster_members (analysis_id);
@@ -0,0 +1,4 @@
+-- Last human edit through PATCH /analyses/{id}; GET /analyses pages through created_at
+ALTER TABLE analyses ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_analyses_created_at ON analyses (created_at, id);
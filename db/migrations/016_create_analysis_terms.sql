@@ -0,0 +1,12 @@
+-- Topics and keywords one row each, matched exactly by their case-folded term_key;
+-- the server indexes analyses stored before this table on startup
+CREATE TABLE IF NOT EXISTS analysis_terms (
+  analysis_id TEXT NOT NULL REFERENCES analyses(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL,
+  position INTEGER NOT NULL,
+  term TEXT NOT NULL,
+  term_key TEXT NOT NULL,
+  PRIMARY KEY (analysis_id, kind, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_analysis_terms_key ON analysis_terms (term_key, kind);
@@ -0,0 +1,6 @@
+-- Set once an analysis' topics and keywords are in analysis_terms, so the startup
+-- backfill skips analyses without any terms instead of revisiting them on every boot
+ALTER TABLE analyses ADD COLUMN IF NOT EXISTS terms_indexed_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE analyses SET terms_indexed_at = now()
+WHERE EXISTS (SELECT 1 FROM analysis_terms t WHERE t.analysis_id = analyses.id);
@@ -0,0 +1,387 @@
+package analyzer
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+)
+
+// ExtractEntities implements rule-based named-entity recognition
+// It is the local fallback when the LLM cannot extract entities, so it favours
+// precision over recall: anything it cannot classify confidently is dropped
+// Algorithm: dates/products by pattern -> capitalised runs -> classify -> resolve aliases -> group
+func ExtractEntities(text string) []models.Entity {
+	var found []candidate
+	var covered [][2]int
+
+	// STEP 1: Pattern-based entities (dates, versioned products) claim their spans first
+	for _, m := range datePattern.FindAllStringIndex(text, -1) {
+		found = append(found, candidate{typ: models.EntityDate, start: m[0], end: m[1]})
+		covered = append(covered, [2]int{m[0], m[1]})
+	}
+	for _, m := range productPattern.FindAllStringIndex(text, -1) {
+		lead := strings.FieldsFunc(text[m[0]:m[1]], func(r rune) bool { return r == ' ' || r == '-' })[0]
+		if overlaps(covered, m[0], m[1]) || notProducts[lead] {
+			continue
+		}
+		found = append(found, candidate{typ: models.EntityProduct, start: m[0], end: m[1]})
+		covered = append(covered, [2]int{m[0], m[1]})
+	}
+
+	// STEP 2: Group capitalised words into runs and classify each run
+	var unknown []candidate
+	for _, r := range capitalisedRuns(text, covered) {
+		if t := classifyRun(r); t != "" {
+			found = append(found, candidate{typ: t, start: r.start, end: r.end})
+		} else {
+			unknown = append(unknown, candidate{start: r.start, end: r.end})
+		}
+	}
+
+	// STEP 3: Resolve short aliases ("Acme" after "Acme Corp", "Smith" after "Jane Smith")
+	aliases := make(map[string]string)
+	ambiguous := make(map[string]bool)
+	for _, c := range found {
+		words := strings.Fields(text[c.start:c.end])
+		var alias string
+		switch {
+		case c.typ == models.EntityPerson && len(words) > 1:
+			alias = words[len(words)-1]
+		case c.typ == models.EntityOrganization && len(words) > 1:
+			alias = words[0]
+		default:
+			continue
+		}
+		key := c.typ + "|" + canonicalEntityName(text[c.start:c.end])
+		if prev, ok := aliases[alias]; ok && prev != key {
+			ambiguous[alias] = true
+		}
+		aliases[alias] = key
+	}
+	for _, u := range unknown {
+		surface := text[u.start:u.end]
+		if key, ok := aliases[surface]; ok && !ambiguous[surface] {
+			typ, _, _ := strings.Cut(key, "|")
+			found = append(found, candidate{typ, u.start, u.end, key})
+		}
+	}
+
+	return groupCandidates(text, found)
+}
+
+// LocateMentions grounds entities returned by an LLM in the source text
+// The LLM only supplies surface strings, so offsets are recomputed locally;
+// entities with an unknown type or no locatable mention are dropped
+func LocateMentions(text string, entities []models.Entity) []models.Entity {
+	var found []candidate
+	for _, e := range entities {
+		typ := models.NormalizeEntityType(e.Type)
+		name := canonicalEntityName(e.Name)
+		if typ == "" || name == "" {
+			continue
+		}
+		key := typ + "|" + name
+
+		surfaces := []string{e.Name}
+		for _, m := range e.Mentions {
+			surfaces = append(surfaces, m.Text)
+		}
+		for _, surface := range surfaces {
+			for _, loc := range findWord(text, strings.TrimSpace(surface)) {
+				found = append(found, candidate{typ, loc[0], loc[1], key})
+			}
+		}
+	}
+	return groupCandidates(text, found)
+}
+
+// EntityID derives the stable identifier shared by every mention of an entity
+func EntityID(entityType, name string) string {
+	sum := sha1.Sum([]byte(entityType + "|" + strings.ToLower(canonicalEntityName(name))))
+	return hex.EncodeToString(sum[:8])
+}
+
+// candidate is an entity span in byte offsets; key overrides the grouping key
+// when the span is an alias of a longer canonical name
+type candidate struct {
+	typ        string
+	start, end int
+	key        string
+}
+
+// groupCandidates merges spans by type + canonical name and converts byte
+// offsets to rune offsets; output is ordered by first mention
+func groupCandidates(text string, found []candidate) []models.Entity {
+	sort.SliceStable(found, func(i, j int) bool { return found[i].start < found[j].start })
+	toRune := runeOffsets(text)
+
+	index := make(map[string]int)
+	seen := make(map[[2]int]bool)
+	var out []models.Entity
+	for _, c := range found {
+		span := [2]int{c.start, c.end}
+		if seen[span] {
+			continue
+		}
+		seen[span] = true
+
+		key := c.key
+		if key == "" {
+			key = c.typ + "|" + canonicalEntityName(text[c.start:c.end])
+		}
+		i, ok := index[key]
+		if !ok {
+			_, name, _ := strings.Cut(key, "|")
+			i = len(out)
+			index[key] = i
+			out = append(out, models.Entity{ID: EntityID(c.typ, name), Type: c.typ, Name: name})
+		}
+		out[i].Mentions = append(out[i].Mentions, models.Mention{
+			Text:  text[c.start:c.end],
+			Start: toRune[c.start],
+			End:   toRune[c.end],
+		})
+	}
+	return out
+}
+
+// canonicalEntityName collapses whitespace and strips possessives and stray punctuation
+func canonicalEntityName(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "'s"), "’s")
+	return strings.Trim(s, " .,;:!?\"'()[]")
+}
+
+func overlaps(covered [][2]int, start, end int) bool {
+	for _, c := range covered {
+		if start < c[1] && c[0] < end {
+			return true
+		}
+	}
+	return false
+}
+
+// run is a sequence of capitalised words (with optional connectors) in byte offsets
+type run struct {
+	words      []string
+	start, end int
+	honorific  bool // preceded by Mr/Dr/Prof etc.
+}
+
+// entityWords returns the byte ranges of the words of text, rejoining words
+// that names hyphenate or join with an ampersand ("Coca-Cola", "AT&T")
+func entityWords(text string) [][2]int {
+	var out [][2]int
+	for _, m := range wordSpans(text) {
+		if n := len(out); n > 0 && m[0] == out[n-1][1]+1 && strings.ContainsRune("&-", rune(text[out[n-1][1]])) {
+			out[n-1][1] = m[1]
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// capitalisedRuns groups adjacent capitalised words separated only by spaces,
+// allowing lowercase connectors ("Bank of England") between them
+func capitalisedRuns(text string, covered [][2]int) []run {
+	var runs []run
+	var cur *run
+	var pendingConnector []int // byte span of a connector waiting for a capitalised word
+	honorific := false
+
+	flush := func() {
+		if cur != nil {
+			runs = append(runs, trimRun(text, *cur))
+		}
+		cur = nil
+		pendingConnector = nil
+	}
+
+	for _, m := range entityWords(text) {
+		word := text[m[0]:m[1]]
+		if overlaps(covered, m[0], m[1]) {
+			flush()
+			honorific = false
+			continue
+		}
+
+		// Only plain whitespace may separate words inside a run
+		if cur != nil {
+			gapStart := cur.end
+			if pendingConnector != nil {
+				gapStart = pendingConnector[1]
+			}
+			if strings.TrimSpace(text[gapStart:m[0]]) != "" {
+				flush()
+			}
+		}
+
+		first, _ := utf8.DecodeRuneInString(word)
+		switch {
+		case unicode.IsUpper(first) && honorifics[strings.ToLower(word)]:
+			flush()
+			honorific = true
+		case unicode.IsUpper(first):
+			if cur == nil {
+				cur = &run{start: m[0], honorific: honorific}
+				honorific = false
+			} else if pendingConnector != nil {
+				cur.words = append(cur.words, text[pendingConnector[0]:pendingConnector[1]])
+				pendingConnector = nil
+			}
+			cur.words = append(cur.words, word)
+			cur.end = m[1]
+		case cur != nil && pendingConnector == nil && connectors[strings.ToLower(word)]:
+			pendingConnector = []int{m[0], m[1]}
+		default:
+			flush()
+			honorific = false
+		}
+	}
+	flush()
+
+	out := runs[:0]
+	for _, r := range runs {
+		if len(r.words) > 0 {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// trimRun drops sentence-initial function words ("The", "In", "When") from the front of a run
+func trimRun(text string, r run) run {
+	for len(r.words) > 0 && leadingFunctionWords[strings.ToLower(r.words[0])] {
+		r.start += len(r.words[0])
+		r.start += len(text[r.start:]) - len(strings.TrimLeft(text[r.start:], " \t\r\n"))
+		r.words = r.words[1:]
+	}
+	return r
+}
+
+// classifyRun assigns an entity type to a capitalised run, or "" when unsure
+func classifyRun(r run) string {
+	first := r.words[0]
+	last := r.words[len(r.words)-1]
+	name := strings.Join(r.words, " ")
+
+	switch {
+	case r.honorific:
+		return models.EntityPerson
+	case len(r.words) > 1 && orgSuffixes[strings.TrimSuffix(last, ".")]:
+		return models.EntityOrganization
+	case len(r.words) > 1 && orgPrefixes[first]:
+		return models.EntityOrganization
+	case locations[name]:
+		return models.EntityLocation
+	case len(r.words) == 1 && isAcronym(first):
+		return models.EntityOrganization
+	case len(r.words) >= 2 && len(r.words) <= 3 && firstNames[first]:
+		return models.EntityPerson
+	}
+	return ""
+}
+
+func isAcronym(word string) bool {
+	n := utf8.RuneCountInString(word)
+	if n < 2 || n > 6 || romanNumeral.MatchString(word) {
+		return false
+	}
+	for _, r := range word {
+		if !unicode.IsUpper(r) && !unicode.IsDigit(r) && r != '&' {
+			return false
+		}
+	}
+	return true
+}
+
+var romanNumeral = regexp.MustCompile(`^[IVXLCDM]+$`)
+
+const monthNames = `(?:Jan(?:uary)?|Feb(?:ruary)?|Mar(?:ch)?|Apr(?:il)?|May|June?|July?|Aug(?:ust)?|Sep(?:t(?:ember)?)?|Oct(?:ober)?|Nov(?:ember)?|Dec(?:ember)?)`
+
+var datePattern = regexp.MustCompile(
+	`\b\d{4}-\d{2}-\d{2}\b` +
+		`|\b\d{1,2}/\d{1,2}/\d{2,4}\b` +
+		`|\b` + monthNames + `\.?\s+\d{1,2}(?:st|nd|rd|th)?(?:,?\s+\d{4})?\b` +
+		`|\b\d{1,2}(?:st|nd|rd|th)?\s+(?:of\s+)?` + monthNames + `\.?(?:,?\s+\d{4})?\b` +
+		`|\b` + monthNames + `\s+\d{4}\b`,
+)
+
+// productPattern matches versioned product names: "iPhone 15 Pro", "Windows 11", "GPT-4"
+var productPattern = regexp.MustCompile(
+	`\b(?:[A-Z][A-Za-z]+|i[A-Z][A-Za-z]+)[ -]\d+(?:\.\d+)*(?:\s(?:Pro|Max|Plus|Ultra|Mini|Air))?\b`,
+)
+
+// notProducts are capitalised words that are commonly followed by a number but are not products
+var notProducts = setOf(
+	"Chapter", "Section", "Page", "Room", "Step", "Level", "Phase", "Part", "Figure", "Table", "Version",
+	"Article", "Day", "Week", "Year", "Item", "Note", "Question", "Route", "Gate", "Floor", "Covid", "COVID",
+)
+
+var honorifics = setOf("mr", "mrs", "ms", "miss", "dr", "prof", "sir", "dame", "madam", "mx")
+
+var connectors = setOf("of", "de", "del", "della", "van", "von", "der", "la", "le", "du", "da")
+
+var leadingFunctionWords = setOf(
+	"the", "a", "an", "this", "that", "these", "those", "it", "its", "we", "i", "he", "she", "they",
+	"in", "on", "at", "by", "for", "from", "to", "with", "but", "and", "or", "if", "when", "while",
+	"after", "before", "as", "our", "their", "his", "her", "my", "your", "yesterday", "today", "tomorrow",
+	"meanwhile", "however", "also", "then", "there", "here", "what", "who", "why", "how",
+	"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday",
+)
+
+var orgSuffixes = setOf(
+	"Inc", "Corp", "Corporation", "Ltd", "LLC", "PLC", "Plc", "GmbH", "AG", "SA", "NV", "BV", "Co",
+	"Company", "Group", "Bank", "University", "Institute", "Foundation", "Association", "Agency",
+	"Ministry", "Department", "Labs", "Laboratories", "Technologies", "Systems", "Holdings",
+	"Partners", "Capital", "Ventures", "Industries", "Media", "Airlines", "Motors", "Council",
+	"Committee", "Commission", "Authority", "Society", "College", "School", "Hospital",
+)
+
+var orgPrefixes = setOf("University", "Bank", "Ministry", "Department", "Institute", "Bureau", "Council", "Committee", "Federal", "National")
+
+var firstNames = setOf(
+	"James", "John", "Robert", "Michael", "William", "David", "Richard", "Joseph", "Thomas", "Charles",
+	"Christopher", "Daniel", "Matthew", "Anthony", "Mark", "Donald", "Steven", "Paul", "Andrew", "Joshua",
+	"Kevin", "Brian", "George", "Timothy", "Ronald", "Edward", "Jason", "Jeffrey", "Ryan", "Jacob",
+	"Gary", "Eric", "Stephen", "Jonathan", "Larry", "Justin", "Scott", "Frank", "Peter", "Samuel",
+	"Mary", "Patricia", "Jennifer", "Linda", "Elizabeth", "Barbara", "Susan", "Jessica", "Sarah", "Karen",
+	"Lisa", "Nancy", "Betty", "Margaret", "Sandra", "Ashley", "Kimberly", "Emily", "Donna", "Michelle",
+	"Carol", "Amanda", "Melissa", "Deborah", "Stephanie", "Rebecca", "Laura", "Sharon", "Cynthia", "Kathleen",
+	"Amy", "Anna", "Angela", "Emma", "Olivia", "Sophia", "Jane", "Alice", "Maria", "Julia",
+	"Elon", "Satya", "Sundar", "Tim", "Jeff", "Bill", "Sam", "Greta", "Angela", "Emmanuel",
+	"Ahmed", "Mohammed", "Fatima", "Wei", "Li", "Hiroshi", "Yuki", "Carlos", "Juan", "Luis",
+	"Pierre", "Hans", "Giuseppe", "Olga", "Ivan", "Priya", "Raj", "Chinedu", "Ngozi", "Gbenga",
+	"Tunde", "Ade", "Kemi", "Kofi", "Ama", "Amara", "Zainab", "Omar", "Aisha", "Noah",
+)
+
+var locations = setOf(
+	"Africa", "Asia", "Europe", "North America", "South America", "Australia", "Antarctica",
+	"United States", "United Kingdom", "USA", "UK", "Canada", "Mexico", "Brazil", "Argentina", "Chile",
+	"Nigeria", "Ghana", "Kenya", "South Africa", "Egypt", "Ethiopia", "Morocco",
+	"France", "Germany", "Spain", "Italy", "Portugal", "Netherlands", "Belgium", "Switzerland", "Austria",
+	"Sweden", "Norway", "Denmark", "Finland", "Poland", "Ireland", "Greece", "Turkey", "Ukraine", "Russia",
+	"China", "Japan", "India", "Pakistan", "Bangladesh", "Indonesia", "Singapore", "Korea", "South Korea",
+	"Vietnam", "Thailand", "Philippines", "Malaysia", "Israel", "Iran", "Iraq", "Saudi Arabia", "UAE",
+	"New Zealand", "London", "Paris", "Berlin", "Madrid", "Rome", "Amsterdam", "Dublin", "Lisbon",
+	"Stockholm", "Vienna", "Zurich", "Geneva", "Brussels", "Moscow", "New York", "Los Angeles",
+	"San Francisco", "Chicago", "Boston", "Seattle", "Austin", "Washington", "Toronto", "Vancouver",
+	"Lagos", "Abuja", "Accra", "Nairobi", "Cairo", "Johannesburg", "Cape Town", "Tokyo", "Beijing",
+	"Shanghai", "Hong Kong", "Delhi", "New Delhi", "Mumbai", "Bangalore", "Dubai", "Sydney", "Melbourne",
+	"California", "Texas", "Florida", "Silicon Valley",
+)
+
+func setOf(items ...string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, it := range items {
+		set[it] = true
+	}
+	return set
+}
@@ -0,0 +1,264 @@
+package analyzer
+
+import (
+	"math"
+	"strings"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+)
+
+const (
+	// GroundingThreshold is the grounding score below which output is flagged as hallucinated
+	GroundingThreshold = 0.5
+	// claimOverlap is the share of content words a summary sentence needs in the source
+	claimOverlap = 0.5
+	// unsupportedClaim is the overlap below which a summary sentence is a likely fabrication
+	unsupportedClaim = 0.2
+	// maxEvidence caps the spans attached to a single keyword or topic
+	maxEvidence = 5
+)
+
+// CheckGrounding validates LLM output against the text it was generated from
+// Algorithm: keywords must occur in the text -> topics need at least one content word
+// in the text -> each summary sentence is scored by the share of its content words
+// found in the text -> the weighted mean is the grounding score
+// When the output was translated (summary/topics in another language), only keywords,
+// which are copied from the text, can be checked lexically
+func CheckGrounding(text, lang string, keywords, topics []string, summary string, translated bool) models.Grounding {
+	src := newSourceIndex(text, lang)
+	g := models.Grounding{
+		Keywords: []models.TermGrounding{},
+		Topics:   []models.TermGrounding{},
+		Summary:  []models.ClaimGrounding{},
+	}
+
+	// STEP 1: Keywords - the phrase itself, or failing that all its content words
+	for _, kw := range keywords {
+		g.Keywords = append(g.Keywords, src.groundTerm(kw, true))
+	}
+
+	// STEP 2: Topics are abstractions, so one shared content word is enough
+	if !translated {
+		for _, topic := range topics {
+			g.Topics = append(g.Topics, src.groundTerm(topic, false))
+		}
+		// STEP 3: Summary sentences by lexical overlap
+		for _, s := range SplitSentences(summary) {
+			if claim, ok := src.groundClaim(s.Text); ok {
+				g.Summary = append(g.Summary, claim)
+			}
+		}
+	}
+
+	// STEP 4: Score and flag
+	var weighted, weights float64
+	add := func(weight, value float64, n int) {
+		if n > 0 {
+			weighted += weight * value
+			weights += weight
+		}
+	}
+	groundedKeywords := countGrounded(g.Keywords)
+	add(0.4, ratio(groundedKeywords, len(g.Keywords)), len(g.Keywords))
+	add(0.2, ratio(countGrounded(g.Topics), len(g.Topics)), len(g.Topics))
+	var overlap float64
+	unsupported := false
+	for _, c := range g.Summary {
+		overlap += c.Overlap
+		if c.Overlap < unsupportedClaim {
+			unsupported = true
+		}
+	}
+	add(0.4, overlap/math.Max(1, float64(len(g.Summary))), len(g.Summary))
+
+	g.Score = 1
+	if weights > 0 {
+		g.Score = math.Round(weighted/weights*1000) / 1000
+	}
+	g.Hallucination = g.Score < GroundingThreshold || unsupported || groundedKeywords*2 < len(g.Keywords)
+	return g
+}
+
+// GroundedConfidence scales the model's confidence by the grounding score and caps
+// it at the score when the output was flagged
+func GroundedConfidence(confidence float64, g models.Grounding) float64 {
+	adjusted := confidence * (0.5 + 0.5*g.Score)
+	if g.Hallucination {
+		adjusted = math.Min(adjusted, g.Score)
+	}
+	return math.Round(adjusted*1000) / 1000
+}
+
+// sourceIndex holds the source tokens and sentences for repeated lookups
+type sourceIndex struct {
+	text      string
+	runes     []rune
+	lang      string
+	tokens    []Token
+	sentences []Sentence
+	// sentenceWords[i] holds the content words of sentences[i]
+	sentenceWords []map[string]bool
+}
+
+func newSourceIndex(text, lang string) *sourceIndex {
+	src := &sourceIndex{
+		text:      text,
+		runes:     []rune(text),
+		lang:      lang,
+		tokens:    TokenizeSpans(text, lang),
+		sentences: SplitSentences(text),
+	}
+	for _, s := range src.sentences {
+		words := make(map[string]bool)
+		for _, t := range src.contentTokens(s.Text) {
+			words[t] = true
+		}
+		src.sentenceWords = append(src.sentenceWords, words)
+	}
+	return src
+}
+
+// contentTokens returns the distinct non-stopword tokens of s
+func (src *sourceIndex) contentTokens(s string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, t := range Tokenize(s, src.lang) {
+		if !IsStopword(t, src.lang) && !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// occurrences returns source tokens matching word, allowing inflected forms
+func (src *sourceIndex) occurrences(word string) []Token {
+	var out []Token
+	for _, t := range src.tokens {
+		if sameStem(word, t.Text) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// groundTerm finds evidence for a keyword (all content words required) or a topic (any one)
+func (src *sourceIndex) groundTerm(term string, requireAll bool) models.TermGrounding {
+	tg := models.TermGrounding{Term: term, Evidence: []models.Span{}}
+
+	// The exact phrase is the strongest evidence
+	if byteSpans := findWord(src.text, strings.TrimSpace(term)); len(byteSpans) > 0 {
+		toRune := runeOffsets(src.text)
+		for _, b := range byteSpans {
+			tg.Evidence = append(tg.Evidence, src.span(toRune[b[0]], toRune[b[1]]))
+			if len(tg.Evidence) == maxEvidence {
+				break
+			}
+		}
+		tg.Grounded = true
+		return tg
+	}
+
+	words := src.contentTokens(term)
+	found := 0
+	for _, w := range words {
+		occ := src.occurrences(w)
+		if len(occ) > 0 {
+			found++
+		}
+		for _, t := range occ {
+			if len(tg.Evidence) == maxEvidence {
+				break
+			}
+			tg.Evidence = append(tg.Evidence, src.span(t.Start, t.End))
+		}
+	}
+	if requireAll {
+		tg.Grounded = len(words) > 0 && found == len(words)
+	} else {
+		tg.Grounded = found > 0
+	}
+	return tg
+}
+
+// groundClaim scores a summary sentence; ok is false when it has no content words
+func (src *sourceIndex) groundClaim(sentence string) (models.ClaimGrounding, bool) {
+	words := src.contentTokens(sentence)
+	if len(words) == 0 {
+		return models.ClaimGrounding{}, false
+	}
+
+	found := 0
+	for _, w := range words {
+		if len(src.occurrences(w)) > 0 {
+			found++
+		}
+	}
+	claim := models.ClaimGrounding{Sentence: sentence, Overlap: math.Round(ratio(found, len(words))*1000) / 1000}
+	claim.Grounded = claim.Overlap >= claimOverlap
+
+	// Evidence: the source sentence sharing the most content words
+	best, bestShared := -1, 0
+	for i, sw := range src.sentenceWords {
+		shared := 0
+		for _, w := range words {
+			for s := range sw {
+				if sameStem(w, s) {
+					shared++
+					break
+				}
+			}
+		}
+		if shared > bestShared {
+			best, bestShared = i, shared
+		}
+	}
+	if best >= 0 {
+		s := src.sentences[best]
+		claim.Evidence = &models.Span{Text: s.Text, Start: s.Start, End: s.End}
+	}
+	return claim, true
+}
+
+// span builds a Span from rune offsets into the source
+func (src *sourceIndex) span(start, end int) models.Span {
+	return models.Span{Text: string(src.runes[start:end]), Start: start, End: end}
+}
+
+// sameStem treats words as equal when identical or when they share a prefix of at
+// least five letters covering most of the shorter word ("electricity"/"electrical")
+func sameStem(a, b string) bool {
+	if a == b {
+		return true
+	}
+	ra, rb := []rune(a), []rune(b)
+	shorter := len(ra)
+	if len(rb) < shorter {
+		shorter = len(rb)
+	}
+	if shorter < 5 {
+		return false
+	}
+	prefix := 0
+	for prefix < shorter && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return prefix >= 5 && prefix*4 >= shorter*3
+}
+
+func countGrounded(terms []models.TermGrounding) int {
+	n := 0
+	for _, t := range terms {
+		if t.Grounded {
+			n++
+		}
+	}
+	return n
+}
+
+func ratio(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
@@ -0,0 +1,130 @@
+package analyzer
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// InjectionThreshold is the score at or above which text is flagged as a likely prompt injection
+const InjectionThreshold = 0.5
+
+// InjectionReport is the outcome of DetectInjection
+type InjectionReport struct {
+	Suspected bool     // Score >= InjectionThreshold
+	Score     float64  // Likelihood that the text tries to instruct the model (0-1)
+	Signals   []string // Names of the heuristics and features that fired, sorted
+}
+
+// DetectInjection scores text for attempts to steer the model that analyses it
+// Algorithm: known attack phrasings (heuristics) -> linear classifier over lexical
+// features -> the higher of the two scores wins
+// Heuristics catch well-known attacks with high precision; the classifier catches
+// paraphrases by combining weaker cues (imperatives aimed at the model, mentions of
+// prompts and output fields, role markers)
+func DetectInjection(text string) InjectionReport {
+	lower := strings.ToLower(text)
+	signals := make(map[string]bool)
+
+	// STEP 1: Heuristics - each pattern carries its own score
+	heuristic := 0.0
+	for _, p := range injectionPatterns {
+		if p.re.MatchString(lower) {
+			signals[p.name] = true
+			heuristic = math.Max(heuristic, p.score)
+		}
+	}
+
+	// STEP 2: Linear classifier (logistic regression with hand-tuned weights)
+	words := wordTokens(lower)
+	z := injectionBias
+	for _, f := range injectionFeatures {
+		if v := f.value(lower, words); v > 0 {
+			signals[f.name] = true
+			z += f.weight * v
+		}
+	}
+	classifier := 1 / (1 + math.Exp(-z))
+
+	score := math.Max(heuristic, classifier)
+	report := InjectionReport{Score: math.Round(score*1000) / 1000}
+	report.Suspected = report.Score >= InjectionThreshold
+	if report.Suspected {
+		for s := range signals {
+			report.Signals = append(report.Signals, s)
+		}
+		sort.Strings(report.Signals)
+	}
+	return report
+}
+
+type injectionPattern struct {
+	name  string
+	score float64
+	re    *regexp.Regexp
+}
+
+var injectionPatterns = []injectionPattern{
+	{"override_instructions", 0.95, regexp.MustCompile(
+		`\b(ignore|disregard|forget|override)\b.{0,30}\b(previous|prior|above|earlier|preceding|all|any|your)\b.{0,20}\b(instructions?|prompts?|rules|directions|guidelines|context)\b`)},
+	{"override_context", 0.9, regexp.MustCompile(`\b(ignore|disregard|forget)\b.{0,20}\b(everything|all|anything)\b.{0,15}\b(above|before|previously|so far)\b`)},
+	{"override_instructions_es", 0.95, regexp.MustCompile(`\b(ignora|olvida)\b.{0,30}\binstrucciones\b`)},
+	{"override_instructions_fr", 0.95, regexp.MustCompile(`\b(ignore[rz]?|oublie[rz]?)\b.{0,30}\b(instructions|consignes)\b`)},
+	{"override_instructions_de", 0.95, regexp.MustCompile(`\b(ignoriere|vergiss)\b.{0,30}\b(anweisungen|instruktionen)\b`)},
+	{"new_instructions", 0.8, regexp.MustCompile(`\b(new|updated|real|actual) (instructions?|task|rules)\s*:`)},
+	{"prompt_exfiltration", 0.85, regexp.MustCompile(
+		`\b(reveal|print|show|repeat|output|leak)\b.{0,30}\b(system prompt|your (instructions|prompt|rules))\b`)},
+	{"role_marker", 0.75, regexp.MustCompile(`(?m)(^\s*(system|assistant|developer)\s*:|<\|im_start\|>|<\|endoftext\|>|\[/?inst\]|<</?sys>>)`)},
+	{"role_reassignment", 0.7, regexp.MustCompile(
+		`\byou are (now|no longer)\b|\bfrom now on,? you\b|\bact as (an?|the) (unrestricted|jailbroken|different)\b|\bdeveloper mode\b|\bjailbreak\b`)},
+	{"output_manipulation", 0.8, regexp.MustCompile(
+		`\b(set|output|return|report|mark|classify|make)\b.{0,25}\b(sentiment|confidence|title|summary|topics?|keywords?)\b.{0,25}\b(as|to|=|:|is)\b`)},
+}
+
+type injectionFeature struct {
+	name   string
+	weight float64
+	value  func(lower string, words []string) float64
+}
+
+// injectionBias keeps ordinary prose well below the threshold (sigmoid(-4) ~ 0.02)
+const injectionBias = -4.0
+
+var injectionFeatures = []injectionFeature{
+	{"override_verb", 1.5, countWords(setOf("ignore", "disregard", "forget", "override", "bypass"))},
+	{"prompt_reference", 0.8, countWords(setOf("instruction", "instructions", "prompt", "prompts", "jailbreak"))},
+	{"model_reference", 0.5, countWords(setOf("ai", "assistant", "model", "llm", "chatgpt", "gpt"))},
+	{"output_field", 0.5, countWords(setOf("sentiment", "confidence", "json", "summary", "title"))},
+	{"second_person", 0.6, func(_ string, words []string) float64 {
+		// Documents talk about things; injections talk to the model
+		n := 0
+		for _, w := range words {
+			if w == "you" || w == "your" {
+				n++
+			}
+		}
+		if len(words) == 0 {
+			return 0
+		}
+		return math.Min(float64(n)/float64(len(words))*20, 2)
+	}},
+	{"must_directive", 0.7, func(lower string, _ []string) float64 {
+		return math.Min(float64(len(directivePattern.FindAllString(lower, -1))), 2)
+	}},
+}
+
+var directivePattern = regexp.MustCompile(`\b(you must|you should|you will|do not|don't|instead,? (say|output|write|respond))\b`)
+
+// countWords returns a feature counting distinct words from set, capped at 2
+func countWords(set map[string]bool) func(string, []string) float64 {
+	return func(_ string, words []string) float64 {
+		seen := make(map[string]bool)
+		for _, w := range words {
+			if set[w] {
+				seen[w] = true
+			}
+		}
+		return math.Min(float64(len(seen)), 2)
+	}
+}
@@ -0,0 +1,305 @@
+package analyzer
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// KeyphraseMethod selects a local keyword extractor
+type KeyphraseMethod string
+
+const (
+	KeyphraseFrequency KeyphraseMethod = "frequency" // Single words by frequency (ExtractKeywords)
+	KeyphraseRAKE      KeyphraseMethod = "rake"      // Rapid Automatic Keyword Extraction
+	KeyphraseTextRank  KeyphraseMethod = "textrank"  // PageRank over the word co-occurrence graph
+)
+
+const (
+	// maxPhraseWords splits longer runs of content words; RAKE would otherwise favour them
+	maxPhraseWords = 4
+	// textRankWindow links words at most this many positions apart
+	textRankWindow  = 3
+	textRankDamping = 0.85
+)
+
+// ParseKeyphraseMethod validates an extractor name; empty selects frequency
+func ParseKeyphraseMethod(name string) (KeyphraseMethod, bool) {
+	switch m := KeyphraseMethod(strings.ToLower(name)); m {
+	case "", KeyphraseFrequency, KeyphraseRAKE, KeyphraseTextRank:
+		return m, true
+	}
+	return "", false
+}
+
+// Keyphrase is a scored single- or multi-word phrase; scores are only comparable
+// within one method
+type Keyphrase struct {
+	Text  string  `json:"text"`
+	Score float64 `json:"score"`
+}
+
+// ExtractKeyphrases returns the topN keyphrases of text using method
+func ExtractKeyphrases(text string, topN int, method KeyphraseMethod, opts KeywordOptions) []Keyphrase {
+	switch method {
+	case KeyphraseRAKE:
+		return ExtractRAKE(text, topN, opts)
+	case KeyphraseTextRank:
+		return ExtractTextRank(text, topN, opts)
+	}
+
+	doc := CountTerms(text, opts)
+	keys := doc.Keys()
+	sort.SliceStable(keys, func(i, j int) bool {
+		return doc.Terms[keys[i]].Count > doc.Terms[keys[j]].Count
+	})
+	var out []Keyphrase
+	for i := 0; i < len(keys) && i < topN; i++ {
+		t := doc.Terms[keys[i]]
+		out = append(out, Keyphrase{Text: t.Display, Score: float64(t.Count)})
+	}
+	return out
+}
+
+// ExtractRAKE scores candidate phrases with RAKE (Rose et al., 2010)
+// Algorithm: split the text into candidate phrases at stopwords and punctuation ->
+// score each word by degree/frequency (words that occur in long phrases score high) ->
+// score each phrase by the sum of its word scores -> select top N
+func ExtractRAKE(text string, topN int, opts KeywordOptions) []Keyphrase {
+	candidates := candidatePhrases(text, opts)
+
+	freq := make(map[string]float64)
+	degree := make(map[string]float64)
+	for _, p := range candidates {
+		for _, w := range p {
+			freq[w.key]++
+			degree[w.key] += float64(len(p))
+		}
+	}
+
+	phrases := groupPhrases(candidates)
+	for _, p := range phrases {
+		for _, key := range p.words {
+			p.score += degree[key] / freq[key]
+		}
+	}
+	return topPhrases(phrases, topN)
+}
+
+// ExtractTextRank scores keyphrases with TextRank (Mihalcea & Tarau, 2004)
+// Algorithm: link content words co-occurring within a small window -> rank words with
+// PageRank -> keep the top third -> collapse adjacent top words into phrases scored by
+// the sum of their word ranks -> select top N
+func ExtractTextRank(text string, topN int, opts KeywordOptions) []Keyphrase {
+	candidates := candidatePhrases(text, opts)
+
+	// STEP 1: Co-occurrence graph over the sequence of content words
+	index := make(map[string]int)
+	var nodes []string
+	var sequence []int
+	for _, p := range candidates {
+		for _, w := range p {
+			i, ok := index[w.key]
+			if !ok {
+				i = len(nodes)
+				index[w.key] = i
+				nodes = append(nodes, w.key)
+			}
+			sequence = append(sequence, i)
+		}
+	}
+	edges := make([]map[int]float64, len(nodes))
+	for i := range edges {
+		edges[i] = make(map[int]float64)
+	}
+	for i, a := range sequence {
+		for j := i + 1; j < len(sequence) && j < i+textRankWindow; j++ {
+			if b := sequence[j]; a != b {
+				edges[a][b]++
+				edges[b][a]++
+			}
+		}
+	}
+
+	// STEP 2: Weighted PageRank
+	rank := pageRank(edges)
+
+	// STEP 3: Keep the top third of the words (at least topN)
+	order := make([]int, len(nodes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return rank[order[i]] > rank[order[j]] })
+	keep := len(nodes) / 3
+	if keep < topN {
+		keep = topN
+	}
+	top := make(map[string]bool)
+	for i := 0; i < len(order) && i < keep; i++ {
+		top[nodes[order[i]]] = true
+	}
+
+	// STEP 4: Adjacent top words form phrases
+	var runs [][]phraseWord
+	for _, p := range candidates {
+		start := 0
+		for i := 0; i <= len(p); i++ {
+			if i < len(p) && top[p[i].key] {
+				continue
+			}
+			if i > start {
+				runs = append(runs, p[start:i])
+			}
+			start = i + 1
+		}
+	}
+	phrases := groupPhrases(runs)
+	for _, p := range phrases {
+		for _, key := range p.words {
+			p.score += rank[index[key]]
+		}
+	}
+	return topPhrases(phrases, topN)
+}
+
+// pageRank runs weighted PageRank to convergence over an undirected graph
+func pageRank(edges []map[int]float64) []float64 {
+	n := len(edges)
+	rank := make([]float64, n)
+	out := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1
+		for _, w := range edges[i] {
+			out[i] += w
+		}
+	}
+	for iter := 0; iter < 100; iter++ {
+		next := make([]float64, n)
+		delta := 0.0
+		for i := range next {
+			sum := 0.0
+			for j, w := range edges[i] {
+				sum += w / out[j] * rank[j]
+			}
+			next[i] = 1 - textRankDamping + textRankDamping*sum
+			delta = math.Max(delta, math.Abs(next[i]-rank[i]))
+		}
+		rank = next
+		if delta < 1e-6 {
+			break
+		}
+	}
+	return rank
+}
+
+// phraseWord is one content word of a candidate phrase
+type phraseWord struct {
+	key     string // Normalized form
+	surface string
+}
+
+// candidatePhrases splits text into runs of adjacent content words
+// Stopwords, filtered tokens (numbers, short words) and punctuation other than
+// hyphens end a phrase, as does a blank line; CJK bigrams overlap, so each is its own phrase
+func candidatePhrases(text string, opts KeywordOptions) [][]phraseWord {
+	lang := opts.Language
+	if lang == "" {
+		lang, _ = DetectLanguage(text)
+	}
+	isStopword := stopwordFilter(lang, opts)
+	runes := []rune(text)
+
+	var phrases [][]phraseWord
+	var current []phraseWord
+	flush := func() {
+		for len(current) > 0 {
+			n := min(len(current), maxPhraseWords)
+			phrases = append(phrases, current[:n])
+			current = current[n:]
+		}
+		current = nil
+	}
+
+	prevEnd := -1
+	for _, t := range TokenizeSpans(text, lang) {
+		// CJK bigrams overlap, so the next token may start before the previous one ends
+		if prevEnd >= 0 && t.Start > prevEnd && !joinable(runes[prevEnd:t.Start]) {
+			flush()
+		}
+		prevEnd = t.End
+
+		w := t.Text
+		cjk := isCJK(w)
+		if isStopword(w) || (!opts.KeepNumbers && isNumeric(w)) ||
+			(!cjk && utf8.RuneCountInString(w) < opts.MinLength) {
+			flush()
+			continue
+		}
+		current = append(current, phraseWord{key: normalizeWord(w, lang, opts.Normalization), surface: w})
+		if cjk {
+			flush()
+		}
+	}
+	flush()
+	return phrases
+}
+
+// joinable reports whether the text between two tokens keeps them in one phrase
+func joinable(gap []rune) bool {
+	newlines := 0
+	for _, r := range gap {
+		switch {
+		case r == '\n':
+			newlines++
+		case r == '-' || unicode.IsSpace(r):
+		default:
+			return false
+		}
+	}
+	return newlines < 2
+}
+
+// scoredPhrase is a distinct phrase with all its occurrences folded together
+type scoredPhrase struct {
+	words    []string // Normalized word keys
+	surfaces map[string]int
+	score    float64
+}
+
+// groupPhrases folds occurrences of the same normalized phrase, in order of first occurrence
+func groupPhrases(occurrences [][]phraseWord) []*scoredPhrase {
+	var out []*scoredPhrase
+	byKey := make(map[string]*scoredPhrase)
+	for _, occ := range occurrences {
+		keys := make([]string, len(occ))
+		surfaces := make([]string, len(occ))
+		for i, w := range occ {
+			keys[i], surfaces[i] = w.key, w.surface
+		}
+		key := strings.Join(keys, " ")
+		p, ok := byKey[key]
+		if !ok {
+			p = &scoredPhrase{words: keys, surfaces: make(map[string]int)}
+			byKey[key] = p
+			out = append(out, p)
+		}
+		p.surfaces[strings.Join(surfaces, " ")]++
+	}
+	return out
+}
+
+// topPhrases sorts phrases by score, keeping first-occurrence order on ties
+func topPhrases(phrases []*scoredPhrase, topN int) []Keyphrase {
+	sort.SliceStable(phrases, func(i, j int) bool { return phrases[i].score > phrases[j].score })
+	var out []Keyphrase
+	for i := 0; i < len(phrases) && i < topN; i++ {
+		p := phrases[i]
+		out = append(out, Keyphrase{
+			Text:  displayForm(strings.Join(p.words, " "), p.surfaces),
+			Score: math.Round(p.score*10000) / 10000,
+		})
+	}
+	return out
+}
@@ -1,45 +1,220 @@
 package analyzer
 
 import (
-	"regexp"
+	"bufio"
+	"io"
 	"sort"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
+// Normalization folds inflected forms of a word together before counting
+type Normalization string
+
+const (
+	NormalizeNone  Normalization = ""      // Count surface forms as they are
+	NormalizeStem  Normalization = "stem"  // Group by Porter stem ("connected", "connection")
+	NormalizeLemma Normalization = "lemma" // Group by dictionary form ("studies", "study")
+)
+
+// KeywordOptions configures local keyword extraction
+type KeywordOptions struct {
+	Language       string                     // ISO 639-1 code; empty detects it from the text
+	Stopwords      map[string]map[string]bool // Per-language lists replacing the built-in ones (see LoadStopwords)
+	ExtraStopwords []string                   // Added to the stopword list (domain noise such as "said")
+	Normalization  Normalization              // How inflected forms are grouped
+	MinLength      int                        // Minimum token length in characters; CJK bigrams are exempt
+	KeepNumbers    bool                       // Keep purely numeric tokens ("2023", "42")
+}
+
+// ParseNormalization reads a normalization name: none, stem or lemma (the default when empty)
+func ParseNormalization(name string) (Normalization, bool) {
+	switch name {
+	case "", string(NormalizeLemma):
+		return NormalizeLemma, true
+	case "none":
+		return NormalizeNone, true
+	case string(NormalizeStem):
+		return NormalizeStem, true
+	}
+	return "", false
+}
+
+// DefaultKeywordOptions drops stopwords, numbers and tokens shorter than three
+// characters, and groups words by lemma
+func DefaultKeywordOptions() KeywordOptions {
+	return KeywordOptions{Normalization: NormalizeLemma, MinLength: 3}
+}
+
 // ExtractTopKeywords implements local keyword extraction using frequency analysis
 // This provides a fallback when LLM-based extraction fails or for performance reasons
-// Algorithm: normalize -> tokenize -> count -> sort -> select top N
+// The language is detected automatically; use ExtractTopKeywordsForLanguage to pin it
 func ExtractTopKeywords(text string, topN int) []string {
-	// STEP 1: Text normalization - convert to lowercase for case-insensitive matching
-	normalized := strings.ToLower(text)
+	return ExtractKeywords(text, topN, DefaultKeywordOptions())
+}
+
+// ExtractTopKeywordsForLanguage extracts keywords using lang's tokenization and stopwords
+func ExtractTopKeywordsForLanguage(text, lang string, topN int) []string {
+	opts := DefaultKeywordOptions()
+	opts.Language = lang
+	return ExtractKeywords(text, topN, opts)
+}
+
+// ExtractKeywords returns the topN most frequent content words of text
+// Algorithm: tokenize (language-aware) -> filter stopwords, numbers and short tokens ->
+// group by normalized form -> sort by frequency -> select top N
+// Ties are broken by first occurrence, then alphabetically, so results are deterministic;
+// each group is shown as its most frequent surface form ("models" + "model" -> "model")
+func ExtractKeywords(text string, topN int, opts KeywordOptions) []string {
+	doc := CountTerms(text, opts)
+	keys := doc.Keys()
+
+	// Sort by frequency, breaking ties deterministically
+	sort.SliceStable(keys, func(i, j int) bool {
+		return doc.Terms[keys[i]].Count > doc.Terms[keys[j]].Count
+	})
+
+	// Select top N, each shown by its most common surface form
+	var top []string
+	for i := 0; i < len(keys) && i < topN; i++ {
+		top = append(top, doc.Terms[keys[i]].Display)
+	}
+	return top
+}
+
+// DocumentTerms are the content terms of one text, grouped by normalized form
+type DocumentTerms struct {
+	Language string
+	Length   int              // Content tokens after filtering
+	Terms    map[string]*Term // Normalized key -> term
+}
 
-	// STEP 2: Tokenization using regex to extract word boundaries
-	re := regexp.MustCompile(`\w+`)
-	words := re.FindAllString(normalized, -1)
+// Term is one group of inflected forms within a document
+type Term struct {
+	Count   int
+	First   int    // Token index of the first occurrence
+	Display string // Most frequent surface form
+}
 
-	// STEP 3: Frequency counting using map for O(1) lookups
-	counts := make(map[string]int)
-	for _, w := range words {
-		counts[w]++
+// CountTerms tokenizes text, filters it as configured by opts and groups the
+// remaining tokens by normalized form; it is the shared first stage of every
+// keyword extractor
+func CountTerms(text string, opts KeywordOptions) DocumentTerms {
+	lang := opts.Language
+	if lang == "" {
+		lang, _ = DetectLanguage(text)
 	}
+	isStopword := stopwordFilter(lang, opts)
+
+	doc := DocumentTerms{Language: lang, Terms: make(map[string]*Term)}
+	surfaces := make(map[string]map[string]int)
+	// STEP 1: Language-aware tokenization (lowercased, Unicode letters and digits)
+	for i, w := range Tokenize(text, lang) {
+		// STEP 2: Filtering
+		if isStopword(w) || (!opts.KeepNumbers && isNumeric(w)) {
+			continue
+		}
+		if !isCJK(w) && utf8.RuneCountInString(w) < opts.MinLength {
+			continue
+		}
 
-	// STEP 4: Sort by frequency (descending) using custom comparator
-	type kv struct {
-		Key   string
-		Value int
+		// STEP 3: Group inflected forms
+		key := normalizeWord(w, lang, opts.Normalization)
+		t, ok := doc.Terms[key]
+		if !ok {
+			t = &Term{First: i}
+			doc.Terms[key] = t
+			surfaces[key] = make(map[string]int)
+		}
+		t.Count++
+		surfaces[key][w]++
+		doc.Length++
 	}
-	var freq []kv
-	for k, v := range counts {
-		freq = append(freq, kv{k, v})
+	for key, t := range doc.Terms {
+		t.Display = displayForm(key, surfaces[key])
+	}
+	return doc
+}
+
+// Keys returns the term keys in order of first occurrence
+func (d DocumentTerms) Keys() []string {
+	keys := make([]string, 0, len(d.Terms))
+	for k := range d.Terms {
+		keys = append(keys, k)
 	}
-	sort.Slice(freq, func(i, j int) bool {
-		return freq[i].Value > freq[j].Value
+	sort.Slice(keys, func(i, j int) bool {
+		return d.Terms[keys[i]].First < d.Terms[keys[j]].First
 	})
+	return keys
+}
 
-	// STEP 5: Select top N most frequent words
-	var top []string
-	for i := 0; i < len(freq) && i < topN; i++ {
-		top = append(top, freq[i].Key)
+// normalizeWord maps w onto the key its group is counted under
+func normalizeWord(w, lang string, n Normalization) string {
+	switch n {
+	case NormalizeStem:
+		return Stem(w, lang)
+	case NormalizeLemma:
+		return Lemmatize(w, lang)
 	}
-	return top
+	return w
+}
+
+// displayForm picks the most frequent surface form, preferring the normalized
+// key itself on ties (it is the dictionary form under lemmatization), then the
+// alphabetically first
+func displayForm(key string, surfaces map[string]int) string {
+	best, bestCount := "", 0
+	for s, c := range surfaces {
+		switch {
+		case c > bestCount,
+			c == bestCount && s == key,
+			c == bestCount && best != key && s < best:
+			best, bestCount = s, c
+		}
+	}
+	return best
+}
+
+// stopwordFilter combines the configured (or built-in) list for lang with the extra stopwords
+func stopwordFilter(lang string, opts KeywordOptions) func(string) bool {
+	fold := newFolder()
+	extra := make(map[string]bool, len(opts.ExtraStopwords))
+	for _, w := range opts.ExtraStopwords {
+		extra[fold(w)] = true
+	}
+	if list, ok := opts.Stopwords[lang]; ok {
+		return func(w string) bool { return list[w] || extra[w] }
+	}
+	return func(w string) bool { return IsStopword(w, lang) || extra[w] }
+}
+
+// isNumeric reports whether w is a number, possibly with separators ("3.14", "1,000")
+func isNumeric(w string) bool {
+	digits := false
+	for _, r := range w {
+		switch {
+		case unicode.IsDigit(r):
+			digits = true
+		case wordBreakOf(r) != wbMidNum && wordBreakOf(r) != wbMidNumLet && wordBreakOf(r) != wbSingleQuote:
+			return false
+		}
+	}
+	return digits
+}
+
+// LoadStopwords reads a stopword list with one word per line
+// Blank lines and lines starting with # are ignored; words are case-folded like tokens
+func LoadStopwords(r io.Reader) (map[string]bool, error) {
+	fold := newFolder()
+	words := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words[fold(line)] = true
+	}
+	return words, scanner.Err()
 }
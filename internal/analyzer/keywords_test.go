@@ -0,0 +1,54 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractKeywordsOptions(t *testing.T) {
+	text := "The model is trained and the models are evaluated. The model is small and the data is clean. " +
+		"In 2023 and 2024 the AI team of 42 used AI in 2023."
+
+	// Stopwords, numbers and short tokens are dropped; "model" and "models" are one
+	// keyword shown as its most frequent form; ties keep their order of first occurrence
+	got := ExtractKeywords(text, 6, DefaultKeywordOptions())
+	if strings.Join(got, ",") != "model,trained,evaluated,small,data,clean" {
+		t.Errorf("expected default keywords, got %v", got)
+	}
+	for i := 0; i < 5; i++ {
+		if again := ExtractKeywords(text, 6, DefaultKeywordOptions()); strings.Join(again, ",") != strings.Join(got, ",") {
+			t.Fatalf("expected a deterministic order, got %v then %v", got, again)
+		}
+	}
+
+	opts := DefaultKeywordOptions()
+	opts.KeepNumbers, opts.MinLength = true, 2
+	if got := ExtractKeywords(text, 3, opts); strings.Join(got, ",") != "model,2023,ai" {
+		t.Errorf("expected numbers and short tokens kept, got %v", got)
+	}
+	opts = DefaultKeywordOptions()
+	opts.Normalization = NormalizeNone
+	if got := ExtractKeywords(text, 2, opts); strings.Join(got, ",") != "model,trained" {
+		t.Errorf("expected surface forms counted apart, got %v", got)
+	}
+	// A configured list replaces the built-in one; extra stopwords are folded and added to it
+	opts = DefaultKeywordOptions()
+	opts.ExtraStopwords = []string{"Trained"}
+	opts.Stopwords = map[string]map[string]bool{"en": {"evaluated": true}}
+	if got := ExtractKeywords(text, 3, opts); strings.Join(got, ",") != "the,model,and" {
+		t.Errorf("expected the configured stopword list, got %v", got)
+	}
+	stopwords, err := LoadStopwords(strings.NewReader("# domain noise\n\nModel\n  data \n"))
+	if err != nil || len(stopwords) != 2 || !stopwords["model"] || !stopwords["data"] {
+		t.Errorf("expected two folded stopwords, got %v (%v)", stopwords, err)
+	}
+}
+
+func TestKeywordsShowSurfaceForms(t *testing.T) {
+	// Terms are grouped by lemma but shown as written
+	text := "Movies and more movies: the movie studies of two studios. Studies continue."
+	got := ExtractKeywords(text, 2, DefaultKeywordOptions())
+	if strings.Join(got, ",") != "movies,studies" {
+		t.Errorf("expected the most frequent surface forms, got %v", got)
+	}
+}
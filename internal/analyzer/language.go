@@ -0,0 +1,174 @@
+package analyzer
+
+import (
+	"strings"
+	"unicode"
+)
+
+// LanguageUndetermined is returned when the text gives no usable signal (ISO 639-2 "und")
+const LanguageUndetermined = "und"
+
+// languageNames maps supported ISO 639-1 codes to English names
+// Used to accept either form in requests and to name languages in prompts
+var languageNames = map[string]string{
+	"en": "English", "es": "Spanish", "fr": "French", "de": "German", "it": "Italian",
+	"pt": "Portuguese", "nl": "Dutch", "ru": "Russian", "uk": "Ukrainian", "zh": "Chinese",
+	"ja": "Japanese", "ko": "Korean", "ar": "Arabic", "fa": "Persian", "he": "Hebrew",
+	"el": "Greek", "hi": "Hindi", "th": "Thai",
+}
+
+// LanguageName returns the English name for a language code, or the code itself if unknown
+func LanguageName(code string) string {
+	if name, ok := languageNames[code]; ok {
+		return name
+	}
+	return code
+}
+
+// NormalizeLanguage maps a language code or English name ("fr", "FR", "French")
+// onto its ISO 639-1 code; ok is false when the language is not recognised
+func NormalizeLanguage(lang string) (code string, ok bool) {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if base, _, found := strings.Cut(lang, "-"); found {
+		lang = base // "pt-BR" -> "pt"
+	}
+	if _, known := languageNames[lang]; known {
+		return lang, true
+	}
+	for code, name := range languageNames {
+		if strings.ToLower(name) == lang {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+// DetectLanguage identifies the language of text offline
+// Algorithm: classify runes by script -> non-Latin scripts map directly to a language ->
+// Latin text is scored by stopword hits plus language-specific letters
+// Confidence is the winning share of the evidence (0-1)
+func DetectLanguage(text string) (lang string, confidence float64) {
+	// STEP 1: Script histogram
+	var letters, latin, han, kana, hangul, cyrillic, arabic, greek, devanagari, hebrew, thai int
+	var ukrainian, persian int
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+			if strings.ContainsRune("іїєґІЇЄҐ", r) {
+				ukrainian++
+			}
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+			if strings.ContainsRune("پچژگ", r) {
+				persian++
+			}
+		case unicode.Is(unicode.Greek, r):
+			greek++
+		case unicode.Is(unicode.Devanagari, r):
+			devanagari++
+		case unicode.Is(unicode.Hebrew, r):
+			hebrew++
+		case unicode.Is(unicode.Thai, r):
+			thai++
+		}
+	}
+	if letters == 0 {
+		return LanguageUndetermined, 0
+	}
+
+	// STEP 2: Non-Latin scripts are decisive on their own
+	share := func(n int) float64 { return float64(n) / float64(letters) }
+	switch {
+	case kana > 0 && kana+han > letters/2:
+		// Japanese mixes kana with kanji; any kana means it is not Chinese
+		return "ja", share(kana + han)
+	case han > letters/2:
+		return "zh", share(han)
+	case hangul > letters/2:
+		return "ko", share(hangul)
+	case cyrillic > letters/2:
+		if ukrainian > 0 {
+			return "uk", share(cyrillic)
+		}
+		return "ru", share(cyrillic)
+	case arabic > letters/2:
+		if persian > 0 {
+			return "fa", share(arabic)
+		}
+		return "ar", share(arabic)
+	case greek > letters/2:
+		return "el", share(greek)
+	case devanagari > letters/2:
+		return "hi", share(devanagari)
+	case hebrew > letters/2:
+		return "he", share(hebrew)
+	case thai > letters/2:
+		return "th", share(thai)
+	case latin == 0:
+		return LanguageUndetermined, 0
+	}
+
+	// STEP 3: Latin script - score stopword hits per language
+	// A stopword shared by k languages ("de", "la") only counts 1/k towards each
+	scores := make(map[string]float64)
+	for _, tok := range wordTokens(strings.ToLower(text)) {
+		// Elided articles ("l'énergie", "dell'anno") are evidence on their own
+		if i := strings.IndexAny(tok, "'’"); i > 0 {
+			tok = tok[:i]
+		}
+		var hits []string
+		for _, code := range latinLanguages {
+			if Stopwords[code][tok] {
+				hits = append(hits, code)
+			}
+		}
+		for _, code := range hits {
+			scores[code] += 1 / float64(len(hits))
+		}
+	}
+
+	// STEP 4: Letters that only occur in some languages are strong hints
+	for code, marks := range distinctiveLetters {
+		for _, r := range strings.ToLower(text) {
+			if strings.ContainsRune(marks, r) {
+				scores[code] += 0.5
+			}
+		}
+	}
+
+	best, total := "", 0.0
+	for _, code := range latinLanguages {
+		total += scores[code]
+		if best == "" || scores[code] > scores[best] {
+			best = code
+		}
+	}
+	if total == 0 {
+		return LanguageUndetermined, 0
+	}
+	return best, scores[best] / total
+}
+
+// latinLanguages is ordered so that ties resolve to the more common language
+var latinLanguages = []string{"en", "es", "fr", "de", "pt", "it", "nl"}
+
+var distinctiveLetters = map[string]string{
+	"es": "ñ¿¡",
+	"fr": "çèêëîœ",
+	"de": "äöüß",
+	"pt": "ãõç",
+	"it": "ìò",
+}
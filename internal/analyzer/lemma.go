@@ -0,0 +1,102 @@
+package analyzer
+
+import "strings"
+
+// Lemmatize maps a lowercase word to its dictionary form for lang
+// English uses an exception list for irregular forms plus suffix rules for
+// regular plurals and -ed/-ing verb forms; other languages are returned unchanged
+// Unlike Stem the result is a real word ("studies" -> "study", "running" -> "run"),
+// at the cost of missing derivations ("connection" stays "connection")
+func Lemmatize(word, lang string) string {
+	if lang != "en" || len(word) <= 3 {
+		return word
+	}
+	if lemma, ok := irregularLemmas[word]; ok {
+		return lemma
+	}
+	if notInflected[word] {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+
+	switch {
+	case strings.HasSuffix(word, "ies") && ieNouns[word[:len(word)-1]]:
+		return word[:len(word)-1] // movies -> movie
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		return word[:len(word)-3] + "y" // studies -> study
+	case strings.HasSuffix(word, "sses"), strings.HasSuffix(word, "shes"), strings.HasSuffix(word, "ches"),
+		strings.HasSuffix(word, "xes"), strings.HasSuffix(word, "zzes"):
+		return word[:len(word)-2] // classes -> class, boxes -> box
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") &&
+		!strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+		return word[:len(word)-1] // models -> model; keeps "process", "status", "analysis"
+	case strings.HasSuffix(word, "ing"):
+		return verbBase(word, word[:len(word)-3])
+	case strings.HasSuffix(word, "ed") && !strings.HasSuffix(word, "eed"):
+		return verbBase(word, word[:len(word)-2])
+	}
+	return word
+}
+
+// verbBase restores the base of a regular verb from stem, the word minus -ed/-ing
+// Algorithm: undouble a final consonant ("stopp" -> "stop") -> restore a silent e
+// after a short consonant-vowel-consonant stem ("hop" -> "hope") -> otherwise keep
+func verbBase(word, stem string) string {
+	if len(stem) < 3 || !strings.ContainsAny(stem, "aeiouy") {
+		return word // "shed": too short, or no vowel left, to be a verb stem
+	}
+	p := &porter{b: []byte(stem), k: len(stem) - 1, j: len(stem) - 1}
+	switch {
+	case p.doublec(p.k) && !strings.ContainsRune("lsz", rune(stem[p.k])):
+		return stem[:len(stem)-1]
+	case strings.HasSuffix(stem, "at"), strings.HasSuffix(stem, "iz"), strings.HasSuffix(stem, "bl"),
+		strings.HasSuffix(stem, "ur"), strings.HasSuffix(stem, "ag"):
+		return stem + "e" // created -> create, organized -> organize, featured -> feature
+	case p.m() == 1 && p.cvc(p.k):
+		return stem + "e" // hoped -> hope, making -> make
+	}
+	return stem
+}
+
+// notInflected look like plurals or verb forms but are base forms
+var notInflected = setOf(
+	"thing", "king", "ring", "sing", "wing", "string", "spring", "swing", "sting", "bring", "morning",
+	"evening", "ceiling", "nothing", "something", "anything", "everything", "during", "darling",
+	"pudding", "wedding", "sibling", "viking", "cling",
+	"hundred", "sacred", "naked", "wicked", "kindred", "need", "speed", "seed", "feed", "breed",
+	"news", "series", "species", "always", "perhaps", "sometimes", "physics", "economics",
+	"mathematics", "politics", "ethics", "lens", "canvas", "atlas", "bias", "gas", "whereas")
+
+// ieNouns end in -ie, so their plurals take -s rather than -y -> -ies
+var ieNouns = setOf(
+	"movie", "cookie", "rookie", "zombie", "hippie", "genie", "calorie", "prairie", "pixie", "smoothie",
+	"selfie", "goalie", "brownie", "auntie", "birdie", "budgie", "collie", "cutie", "foodie", "freebie",
+	"groupie", "hoodie", "indie", "junkie", "magpie", "newbie", "pinkie", "reverie", "sortie", "techie",
+	"veggie", "yuppie", "lingerie", "menagerie", "coterie", "eyrie")
+
+// irregularLemmas covers common irregular English nouns, verbs and comparatives
+var irregularLemmas = map[string]string{
+	"children": "child", "people": "person", "men": "man", "women": "woman", "mice": "mouse",
+	"feet": "foot", "teeth": "tooth", "geese": "goose", "data": "data", "criteria": "criterion",
+	"phenomena": "phenomenon", "analyses": "analysis", "theses": "thesis", "crises": "crisis",
+	"indices": "index", "matrices": "matrix", "lives": "life", "wives": "wife", "knives": "knife",
+	"leaves": "leaf", "halves": "half", "shelves": "shelf", "wolves": "wolf", "thieves": "thief",
+	"was": "be", "were": "be", "been": "be", "being": "be", "is": "be", "are": "be", "am": "be",
+	"used": "use", "using": "use", "uses": "use", "has": "have", "had": "have", "having": "have",
+	"does": "do", "did": "do", "done": "do",
+	"went": "go", "gone": "go", "goes": "go", "made": "make", "said": "say", "took": "take", "taken": "take",
+	"came": "come", "saw": "see", "seen": "see", "knew": "know", "known": "know", "got": "get",
+	"gotten": "get", "gave": "give", "given": "give", "found": "find", "thought": "think", "told": "tell",
+	"became": "become", "left": "leave", "felt": "feel", "brought": "bring", "began": "begin", "begun": "begin",
+	"kept": "keep", "held": "hold", "wrote": "write", "written": "write", "stood": "stand", "heard": "hear",
+	"meant": "mean", "met": "meet", "ran": "run", "paid": "pay", "sat": "sit", "spoke": "speak",
+	"spoken": "speak", "led": "lead", "grew": "grow", "grown": "grow", "lost": "lose", "fell": "fall",
+	"fallen": "fall", "sent": "send", "built": "build", "understood": "understand", "chose": "choose",
+	"chosen": "choose", "bought": "buy", "sold": "sell", "caught": "catch", "taught": "teach",
+	"fought": "fight", "drove": "drive", "driven": "drive", "rose": "rise", "risen": "rise",
+	"better": "good", "best": "good", "worse": "bad", "worst": "bad",
+}
@@ -0,0 +1,37 @@
+package analyzer
+
+import "testing"
+
+func TestLemmatize(t *testing.T) {
+	tests := []struct {
+		word, lang, want string
+	}{
+		{"studies", "en", "study"},
+		{"movies", "en", "movie"},
+		{"cookies", "en", "cookie"},
+		{"zombies", "en", "zombie"},
+		{"ties", "en", "tie"},
+		{"classes", "en", "class"},
+		{"boxes", "en", "box"},
+		{"models", "en", "model"},
+		{"status", "en", "status"},
+		{"analysis", "en", "analysis"},
+		{"series", "en", "series"},
+		{"running", "en", "run"},
+		{"hoped", "en", "hope"},
+		{"created", "en", "create"},
+		{"stopped", "en", "stop"},
+		{"morning", "en", "morning"},
+		{"needed", "en", "need"},
+		{"children", "en", "child"},
+		{"went", "en", "go"},
+		{"bus", "en", "bus"},
+		{"naïves", "en", "naïves"},
+		{"movies", "fr", "movies"},
+	}
+	for _, tt := range tests {
+		if got := Lemmatize(tt.word, tt.lang); got != tt.want {
+			t.Errorf("Lemmatize(%q, %q) = %q, want %q", tt.word, tt.lang, got, tt.want)
+		}
+	}
+}
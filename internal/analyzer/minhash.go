@@ -0,0 +1,125 @@
+package analyzer
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+const (
+	// MinHashSize is the number of hash functions in a signature
+	MinHashSize = 64
+	// MinHashBands is the number of LSH bands a signature is indexed by, of MinHashSize/MinHashBands rows each
+	// With 16 bands of 4 rows, documents with a Jaccard similarity of 0.7 are found 99% of the time
+	MinHashBands = 16
+	// DefaultDuplicateThreshold is the estimated shingle overlap from which texts are near copies;
+	// one edited word in a 50-word article still scores about 0.9
+	DefaultDuplicateThreshold = 0.7
+
+	minHashRows = MinHashSize / MinHashBands
+)
+
+// minHashSeeds give each hash function its own permutation of shingle hashes
+var minHashSeeds = func() [MinHashSize]uint64 {
+	var seeds [MinHashSize]uint64
+	x := uint64(0x9e3779b97f4a7c15)
+	for i := range seeds {
+		x = splitmix64(x)
+		seeds[i] = x
+	}
+	return seeds
+}()
+
+// Signature is the MinHash of a document's word shingles (Broder, 1997): the share of
+// positions at which two signatures agree estimates the Jaccard similarity of the shingle sets
+type Signature [MinHashSize]uint32
+
+// MinHash signs text over overlapping word bigrams
+// Algorithm: tokenize -> hash each distinct bigram (FNV-1a) -> for each of the MinHashSize
+// seeded hash functions, keep the minimum over all bigrams
+// Single-word texts use the word itself; a text without words has the all-ones signature
+func MinHash(text, lang string) Signature {
+	tokens := Tokenize(text, lang)
+	shingles := make(map[uint64]bool)
+	if len(tokens) == 1 {
+		shingles[hashString(tokens[0])] = true
+	}
+	for i := 0; i+1 < len(tokens); i++ {
+		shingles[hashString(tokens[i]+" "+tokens[i+1])] = true
+	}
+
+	var sig Signature
+	for i := range sig {
+		sig[i] = math.MaxUint32
+	}
+	for h := range shingles {
+		for i, seed := range minHashSeeds {
+			if v := uint32(splitmix64(h ^ seed)); v < sig[i] {
+				sig[i] = v
+			}
+		}
+	}
+	return sig
+}
+
+// Similarity estimates the Jaccard similarity (0-1) of the texts behind two signatures
+func (s Signature) Similarity(other Signature) float64 {
+	same := 0
+	for i := range s {
+		if s[i] == other[i] {
+			same++
+		}
+	}
+	return round3(float64(same) / MinHashSize)
+}
+
+// Bands hashes each band of rows into one value for LSH lookup: two signatures are
+// candidates when they agree on every row of at least one band
+func (s Signature) Bands() [MinHashBands]int64 {
+	var out [MinHashBands]int64
+	buf := make([]byte, 4*minHashRows)
+	for band := range out {
+		for row := 0; row < minHashRows; row++ {
+			binary.LittleEndian.PutUint32(buf[4*row:], s[band*minHashRows+row])
+		}
+		h := fnv.New64a()
+		h.Write(buf)
+		out[band] = int64(h.Sum64())
+	}
+	return out
+}
+
+// Bytes encodes the signature for storage as little-endian uint32s
+func (s Signature) Bytes() []byte {
+	buf := make([]byte, 4*MinHashSize)
+	for i, v := range s {
+		binary.LittleEndian.PutUint32(buf[4*i:], v)
+	}
+	return buf
+}
+
+// SignatureFromBytes decodes a stored signature; ok is false when b has the wrong length
+func SignatureFromBytes(b []byte) (Signature, bool) {
+	var s Signature
+	if len(b) != 4*MinHashSize {
+		return s, false
+	}
+	for i := range s {
+		s[i] = binary.LittleEndian.Uint32(b[4*i:])
+	}
+	return s, true
+}
+
+func hashString(v string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(v))
+	return h.Sum64()
+}
+
+// splitmix64 is a fast, well-mixed 64-bit hash step (Steele et al., 2014)
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
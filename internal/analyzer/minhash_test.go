@@ -0,0 +1,109 @@
+package analyzer
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+const article = "Solar panels convert sunlight into electricity using photovoltaic cells made of silicon. " +
+	"Homeowners install them on rooftops to cut energy bills and reduce carbon emissions. " +
+	"Battery storage lets households keep surplus power for the evening, when demand peaks " +
+	"and the grid relies more on gas plants. Falling prices have made solar the cheapest source of new power."
+
+func TestMinHashStable(t *testing.T) {
+	sig := MinHash("The quick brown fox jumps over the lazy dog", "en")
+	if again := MinHash("The quick brown fox jumps over the lazy dog", "en"); again != sig {
+		t.Fatal("MinHash is not deterministic")
+	}
+	// Stored signatures are compared with new ones, so the hashing must not change between releases
+	if sig[0] != 600487262 || sig[1] != 562505105 || sig[MinHashSize-1] != 269608970 {
+		t.Errorf("signature changed: %d %d ... %d", sig[0], sig[1], sig[MinHashSize-1])
+	}
+	if band := sig.Bands()[0]; band != 5751843812328949858 {
+		t.Errorf("band hash changed: %d", band)
+	}
+	decoded, ok := SignatureFromBytes(sig.Bytes())
+	if !ok || decoded != sig {
+		t.Error("signature does not survive a Bytes round trip")
+	}
+	if _, ok := SignatureFromBytes(sig.Bytes()[1:]); ok {
+		t.Error("SignatureFromBytes accepted a short buffer")
+	}
+}
+
+func TestMinHashNearCopies(t *testing.T) {
+	sig := MinHash(article, "en")
+	edited := MinHash(strings.Replace(article, "cheapest", "cheaper", 1), "en")
+	other := MinHash("The central bank raised interest rates again to curb inflation, "+
+		"and mortgage lenders followed within days.", "en")
+
+	if sim := sig.Similarity(edited); sim < DefaultDuplicateThreshold {
+		t.Errorf("one edited word: similarity %v, want at least %v", sim, DefaultDuplicateThreshold)
+	}
+	if !sharesBand(sig, edited) {
+		t.Error("near copies share no LSH band")
+	}
+	if sim := sig.Similarity(other); sim >= DefaultDuplicateThreshold {
+		t.Errorf("unrelated text: similarity %v, want below %v", sim, DefaultDuplicateThreshold)
+	}
+	if sharesBand(sig, other) {
+		t.Error("unrelated texts share an LSH band")
+	}
+}
+
+func TestSignatureThresholdBoundary(t *testing.T) {
+	// 0.7 of 64 rows is 44.8: 45 agreeing rows reach the threshold, 44 do not
+	at := math.Ceil(DefaultDuplicateThreshold * MinHashSize)
+	tests := []struct {
+		same int
+		want bool
+	}{
+		{MinHashSize, true},
+		{int(at), true},
+		{int(at) - 1, false},
+		{0, false},
+	}
+	var base Signature
+	for i := range base {
+		base[i] = uint32(i)
+	}
+	for _, tt := range tests {
+		other := base
+		for i := tt.same; i < MinHashSize; i++ {
+			other[i] = base[i] + 1000
+		}
+		sim := base.Similarity(other)
+		if got := sim >= DefaultDuplicateThreshold; got != tt.want {
+			t.Errorf("%d agreeing rows: similarity %v, duplicate %v, want %v", tt.same, sim, got, tt.want)
+		}
+	}
+}
+
+func TestMinHashEmptyText(t *testing.T) {
+	empty := MinHash("", "en")
+	for i, v := range empty {
+		if v != math.MaxUint32 {
+			t.Fatalf("row %d of an empty text's signature is %d, want all ones", i, v)
+		}
+	}
+	if punct := MinHash(" ... !? ", "en"); punct != empty {
+		t.Error("a text without words should sign like the empty text")
+	}
+	if sim := empty.Similarity(MinHash(article, "en")); sim != 0 {
+		t.Errorf("empty text vs article: similarity %v, want 0", sim)
+	}
+	if single := MinHash("solar", "en"); single == empty {
+		t.Error("a single-word text should be signed by its word")
+	}
+}
+
+func sharesBand(a, b Signature) bool {
+	ab, bb := a.Bands(), b.Bands()
+	for i := range ab {
+		if ab[i] == bb[i] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,297 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Names of the stages provided by this package
+const (
+	StageNormalize = "normalize"
+	StageLanguage  = "language"
+	StageInjection = "injection"
+	StageValidate  = "validate"
+)
+
+// Document is the state a Pipeline hands from stage to stage
+// Stages read what earlier ones produced and add their own output
+type Document struct {
+	Text           string          // Text the stages work on; offsets in Analysis refer to it
+	Language       string          // Detected language, LanguageUndetermined when unknown
+	TargetLanguage string          // Requested output language; empty keeps the source language
+	Analysis       models.Analysis // Output so far
+	Local          models.Analysis // Output of the local extractors, combined with the LLM's per field
+
+	RedactionTokens map[string]string // Token -> original value when redaction tokenizes
+	Signature       *Signature        // MinHash of Text; nil when not computed
+	Terms           DocumentTerms     // Term counts for corpus statistics
+	Vector          []float32         // Embedding of Text; nil when not computed
+}
+
+// Stage is one step of an analysis pipeline
+// Run should only change doc once it has succeeded, so a skipped stage leaves no partial output
+type Stage interface {
+	Name() string
+	Run(ctx context.Context, doc *Document) error
+}
+
+// Degrader is implemented by stages that can produce reduced output after an error,
+// e.g. local fields in place of the LLM's
+type Degrader interface {
+	Degrade(ctx context.Context, doc *Document, err error) error
+}
+
+// ErrorPolicy decides what a pipeline does when a stage fails
+type ErrorPolicy string
+
+const (
+	PolicyFail    ErrorPolicy = "fail"    // Stop and return the error
+	PolicySkip    ErrorPolicy = "skip"    // Record the error and go on without the stage's output
+	PolicyDegrade ErrorPolicy = "degrade" // Record the error and go on with the stage's fallback output, if any
+)
+
+// Stage outcomes reported per run
+const (
+	StatusOK       = "ok"
+	StatusSkipped  = "skipped"
+	StatusDegraded = "degraded"
+	StatusFailed   = "failed"
+	StatusDisabled = "disabled"
+)
+
+// ParseErrorPolicy accepts fail, skip and degrade (case-insensitive)
+func ParseErrorPolicy(name string) (ErrorPolicy, bool) {
+	switch p := ErrorPolicy(strings.ToLower(strings.TrimSpace(name))); p {
+	case PolicyFail, PolicySkip, PolicyDegrade:
+		return p, true
+	}
+	return "", false
+}
+
+// Terminal is implemented by stage errors that are decisions rather than failures,
+// e.g. rejecting a duplicate: they stop the run whatever the stage's policy
+type Terminal interface {
+	Terminal() bool
+}
+
+// StageError is returned by Run when a stage with the fail policy errs
+type StageError struct {
+	Stage string
+	Err   error
+}
+
+func (e *StageError) Error() string { return e.Stage + ": " + e.Err.Error() }
+
+func (e *StageError) Unwrap() error { return e.Err }
+
+// Pipeline runs named stages in order; every stage fails the run on error
+// unless its policy says otherwise
+type Pipeline struct {
+	stages   []Stage
+	policies map[string]ErrorPolicy
+}
+
+// NewPipeline returns a pipeline of stages, run in the given order
+func NewPipeline(stages ...Stage) *Pipeline {
+	return &Pipeline{stages: stages, policies: make(map[string]ErrorPolicy)}
+}
+
+// Stages returns the stage names in run order
+func (p *Pipeline) Stages() []string {
+	names := make([]string, len(p.stages))
+	for i, st := range p.stages {
+		names[i] = st.Name()
+	}
+	return names
+}
+
+// Has reports whether the pipeline has a stage called name
+func (p *Pipeline) Has(name string) bool {
+	for _, st := range p.stages {
+		if st.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Policy returns the error policy of the named stage, PolicyFail by default
+func (p *Pipeline) Policy(name string) ErrorPolicy {
+	if policy, ok := p.policies[name]; ok {
+		return policy
+	}
+	return PolicyFail
+}
+
+// SetPolicy sets the error policy of the named stage
+func (p *Pipeline) SetPolicy(name string, policy ErrorPolicy) error {
+	if !p.Has(name) {
+		return fmt.Errorf("unknown stage %q", name)
+	}
+	p.policies[name] = policy
+	return nil
+}
+
+// Select returns a pipeline of the named stages, kept in this pipeline's order,
+// with the same policies
+func (p *Pipeline) Select(names []string) (*Pipeline, error) {
+	keep := make(map[string]bool, len(names))
+	for _, name := range names {
+		if !p.Has(name) {
+			return nil, fmt.Errorf("unknown stage %q", name)
+		}
+		keep[name] = true
+	}
+	selected := NewPipeline()
+	for _, st := range p.stages {
+		if keep[st.Name()] {
+			selected.stages = append(selected.stages, st)
+		}
+	}
+	for name, policy := range p.policies {
+		selected.policies[name] = policy
+	}
+	return selected, nil
+}
+
+// RunOptions adjusts one run, e.g. from request parameters
+type RunOptions struct {
+	Disabled map[string]bool        // Stages not to run
+	Policies map[string]ErrorPolicy // Overrides of the pipeline's policies
+}
+
+// Run passes doc through the stages and reports each stage's outcome and duration
+// The reports cover the stages up to and including a failed one; Terminal errors fail
+// the run under any policy
+func (p *Pipeline) Run(ctx context.Context, doc *Document, opts RunOptions) ([]models.StageReport, error) {
+	reports := make([]models.StageReport, 0, len(p.stages))
+	for _, st := range p.stages {
+		name := st.Name()
+		policy := p.Policy(name)
+		if override, ok := opts.Policies[name]; ok {
+			policy = override
+		}
+		report := models.StageReport{Stage: name, Status: StatusOK, Policy: string(policy)}
+		if opts.Disabled[name] {
+			report.Status = StatusDisabled
+			reports = append(reports, report)
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return reports, &StageError{Stage: name, Err: err}
+		}
+
+		start := time.Now()
+		err := st.Run(ctx, doc)
+		if err != nil {
+			report.Error = err.Error()
+			var terminal Terminal
+			switch {
+			case errors.As(err, &terminal) && terminal.Terminal():
+				report.Status = StatusFailed
+			case policy == PolicySkip:
+				report.Status = StatusSkipped
+			case policy == PolicyDegrade:
+				report.Status = StatusDegraded
+				if d, ok := st.(Degrader); ok {
+					if derr := d.Degrade(ctx, doc, err); derr != nil {
+						report.Error += "; fallback: " + derr.Error()
+					}
+				}
+			default:
+				report.Status = StatusFailed
+			}
+		}
+		report.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+		reports = append(reports, report)
+		if report.Status == StatusFailed {
+			return reports, &StageError{Stage: name, Err: err}
+		}
+	}
+	return reports, nil
+}
+
+// stage adapts a function to the Stage interface
+type stage struct {
+	name string
+	run  func(ctx context.Context, doc *Document) error
+}
+
+func (s stage) Name() string { return s.name }
+
+func (s stage) Run(ctx context.Context, doc *Document) error { return s.run(ctx, doc) }
+
+// NewStage returns a stage that calls run
+func NewStage(name string, run func(ctx context.Context, doc *Document) error) Stage {
+	return stage{name: name, run: run}
+}
+
+// NormalizeStage puts the text in NFC, turns CRLF and CR line breaks into LF,
+// drops control characters other than tabs, zero-width spaces and byte order marks,
+// and trims surrounding whitespace; it fails on text that ends up empty
+func NormalizeStage() Stage {
+	return NewStage(StageNormalize, func(ctx context.Context, doc *Document) error {
+		text := NormalizeText(doc.Text)
+		if text == "" {
+			return fmt.Errorf("text is empty after normalization")
+		}
+		doc.Text = text
+		return nil
+	})
+}
+
+// NormalizeText is the text transformation of NormalizeStage
+func NormalizeText(text string) string {
+	text = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(text)
+	text = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			return r
+		case unicode.Is(unicode.Cc, r), r == '\u200B', r == '\uFEFF':
+			return -1
+		}
+		return r
+	}, text)
+	return strings.TrimSpace(norm.NFC.String(text))
+}
+
+// LanguageStage detects the language of the text offline
+func LanguageStage() Stage {
+	return NewStage(StageLanguage, func(ctx context.Context, doc *Document) error {
+		doc.Language, _ = DetectLanguage(doc.Text)
+		doc.Analysis.Language = doc.Language
+		return nil
+	})
+}
+
+// InjectionStage flags text that tries to instruct the model; prompts should still
+// fence the text off as untrusted data, so this records the attempt for review
+func InjectionStage() Stage {
+	return NewStage(StageInjection, func(ctx context.Context, doc *Document) error {
+		report := DetectInjection(doc.Text)
+		doc.Analysis.InjectionSuspected = report.Suspected
+		doc.Analysis.InjectionScore = report.Score
+		doc.Analysis.InjectionSignals = report.Signals
+		return nil
+	})
+}
+
+// ValidateStage checks keywords, topics and summary against the text and lowers
+// the confidence when they are not supported by it
+func ValidateStage() Stage {
+	return NewStage(StageValidate, func(ctx context.Context, doc *Document) error {
+		a := &doc.Analysis
+		translated := doc.TargetLanguage != "" && doc.TargetLanguage != doc.Language
+		grounding := CheckGrounding(doc.Text, doc.Language, a.Keywords, a.Topics, a.Summary, translated)
+		a.Grounding = &grounding
+		a.Confidence = GroundedConfidence(a.Confidence, grounding)
+		return nil
+	})
+}
@@ -0,0 +1,169 @@
+package analyzer
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// wordBreak is the Word_Break property class of a character (UAX #29)
+type wordBreak uint8
+
+const (
+	wbOther wordBreak = iota
+	wbCR
+	wbLF
+	wbNewline
+	wbExtend // combining marks and ZWNJ; also ZWJ and Format, which WB4 treats alike
+	wbALetter
+	wbHebrewLetter
+	wbNumeric
+	wbKatakana
+	wbExtendNumLet
+	wbMidLetter
+	wbMidNum
+	wbMidNumLet
+	wbSingleQuote
+	wbDoubleQuote
+)
+
+// Punctuation that may occur inside a word or number ("can't", "S:t", "3.14", "1,000")
+var midPunctuation = map[rune]wordBreak{
+	':': wbMidLetter, '\u00B7': wbMidLetter, '\u0387': wbMidLetter, '\u055F': wbMidLetter, '\u05F4': wbMidLetter,
+	'\u2027': wbMidLetter, '\uFE13': wbMidLetter, '\uFE55': wbMidLetter, '\uFF1A': wbMidLetter,
+	',': wbMidNum, ';': wbMidNum, '\u037E': wbMidNum, '\u0589': wbMidNum, '\u060C': wbMidNum, '\u060D': wbMidNum,
+	'\u066C': wbMidNum, '\u07F8': wbMidNum, '\u2044': wbMidNum, '\uFE10': wbMidNum, '\uFE14': wbMidNum,
+	'\uFE50': wbMidNum, '\uFE54': wbMidNum, '\uFF0C': wbMidNum, '\uFF1B': wbMidNum,
+	'.': wbMidNumLet, '\u2018': wbMidNumLet, '\u2019': wbMidNumLet, '\u2024': wbMidNumLet, '\uFE52': wbMidNumLet,
+	'\uFF07': wbMidNumLet, '\uFF0E': wbMidNumLet,
+	'\'': wbSingleQuote, '"': wbDoubleQuote,
+}
+
+// wordBreakOf classifies r, with one tailoring: UAX #29 leaves Thai, Lao, Khmer
+// and Myanmar, written without spaces, to dictionaries; without one their
+// letters are ALetter, so a run of them is a single word
+func wordBreakOf(r rune) wordBreak {
+	switch {
+	case r == '\r':
+		return wbCR
+	case r == '\n':
+		return wbLF
+	case r == '\v' || r == '\f' || r == '\u0085' || r == '\u2028' || r == '\u2029':
+		return wbNewline
+	case r == '\u200C' || r == '\u200D' || unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		return wbExtend
+	case r == '\u200B':
+		return wbOther
+	case unicode.Is(unicode.Cf, r):
+		return wbExtend
+	case unicode.Is(unicode.Katakana, r) || r == '\u30FC' || r == '\uFF70' || r == '\u309B' || r == '\u309C':
+		return wbKatakana
+	case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r):
+		return wbOther // Ideographs and hiragana break on both sides
+	case unicode.Is(unicode.Hebrew, r) && unicode.IsLetter(r):
+		return wbHebrewLetter
+	case unicode.IsLetter(r) || unicode.Is(unicode.Nl, r):
+		return wbALetter
+	case unicode.IsDigit(r):
+		return wbNumeric
+	case unicode.Is(unicode.Pc, r) || r == '\u202F':
+		return wbExtendNumLet
+	}
+	if wb, ok := midPunctuation[r]; ok {
+		return wb
+	}
+	return wbOther
+}
+
+func isAHLetter(wb wordBreak) bool { return wb == wbALetter || wb == wbHebrewLetter }
+
+func isMidNumLetQ(wb wordBreak) bool { return wb == wbMidNumLet || wb == wbSingleQuote }
+
+// wordUnit is a character with the combining marks and format characters attached to it (WB4)
+type wordUnit struct {
+	class      wordBreak
+	start, end int // Byte offsets
+}
+
+// wordSpans returns the byte ranges of the words of text: the UAX #29 word
+// segments containing a letter or digit. Punctuation and space segments are dropped.
+// Ideographs and hiragana are single-character segments
+func wordSpans(text string) [][2]int {
+	var units []wordUnit
+	for i, r := range text {
+		wb := wordBreakOf(r)
+		end := i + utf8.RuneLen(r)
+		// WB4: marks and format characters extend anything but a line break
+		if wb == wbExtend && len(units) > 0 {
+			if prev := &units[len(units)-1]; prev.class != wbCR && prev.class != wbLF && prev.class != wbNewline {
+				prev.end = end
+				continue
+			}
+		}
+		units = append(units, wordUnit{class: wb, start: i, end: end})
+	}
+
+	class := func(i int) wordBreak {
+		if i < 0 || i >= len(units) {
+			return wbOther
+		}
+		return units[i].class
+	}
+	var spans [][2]int
+	segStart := 0
+	for i := range units {
+		if i > 0 && wordBoundary(class(i-2), class(i-1), class(i), class(i+1)) {
+			spans = appendWord(spans, text, units[segStart].start, units[i-1].end)
+			segStart = i
+		}
+	}
+	if len(units) > 0 {
+		spans = appendWord(spans, text, units[segStart].start, units[len(units)-1].end)
+	}
+	return spans
+}
+
+// wordBoundary applies the UAX #29 rules WB3-WB13b to the position between b and c,
+// where a precedes b and d follows c
+func wordBoundary(a, b, c, d wordBreak) bool {
+	switch {
+	case b == wbCR && c == wbLF: // WB3
+		return false
+	case b == wbCR || b == wbLF || b == wbNewline || c == wbCR || c == wbLF || c == wbNewline: // WB3a, WB3b
+		return true
+	case isAHLetter(b) && isAHLetter(c): // WB5
+		return false
+	case isAHLetter(b) && (c == wbMidLetter || isMidNumLetQ(c)) && isAHLetter(d): // WB6
+		return false
+	case isAHLetter(a) && (b == wbMidLetter || isMidNumLetQ(b)) && isAHLetter(c): // WB7
+		return false
+	case b == wbHebrewLetter && c == wbSingleQuote: // WB7a
+		return false
+	case b == wbHebrewLetter && c == wbDoubleQuote && d == wbHebrewLetter: // WB7b
+		return false
+	case a == wbHebrewLetter && b == wbDoubleQuote && c == wbHebrewLetter: // WB7c
+		return false
+	case (b == wbNumeric || isAHLetter(b)) && (c == wbNumeric || isAHLetter(c)): // WB8, WB9, WB10
+		return false
+	case a == wbNumeric && (b == wbMidNum || isMidNumLetQ(b)) && c == wbNumeric: // WB11
+		return false
+	case b == wbNumeric && (c == wbMidNum || isMidNumLetQ(c)) && d == wbNumeric: // WB12
+		return false
+	case b == wbKatakana && c == wbKatakana: // WB13
+		return false
+	case (isAHLetter(b) || b == wbNumeric || b == wbKatakana || b == wbExtendNumLet) && c == wbExtendNumLet: // WB13a
+		return false
+	case b == wbExtendNumLet && (isAHLetter(c) || c == wbNumeric || c == wbKatakana): // WB13b
+		return false
+	}
+	return true // WB999
+}
+
+// appendWord adds text[start:end] to spans if it contains a letter or digit
+func appendWord(spans [][2]int, text string, start, end int) [][2]int {
+	for _, r := range text[start:end] {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			return append(spans, [2]int{start, end})
+		}
+	}
+	return spans
+}
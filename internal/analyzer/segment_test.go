@@ -0,0 +1,97 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		lang string
+		text string
+		want []string
+	}{
+		{"chinese bigrams", "zh", "我爱北京", []string{"我爱", "爱北", "北京"}},
+		{"single ideograph", "zh", "猫", []string{"猫"}},
+		{"japanese mixed kana", "ja", "東京のコーヒー", []string{"東京", "京の", "のコ", "コー", "ーヒ", "ヒー"}},
+		{"cjk runs split by punctuation", "zh", "北京，上海", []string{"北京", "上海"}},
+		{"french elision", "fr", "L'homme d’affaires", []string{"homme", "affaires"}},
+		{"french long prefix kept", "fr", "aujourd'hui", []string{"aujourd'hui"}},
+		{"italian elision", "it", "dell'anno all'epoca", []string{"anno", "epoca"}},
+		{"elision left alone in english", "en", "l'homme", []string{"l'homme"}},
+		{"english possessive", "en", "The company's CEO", []string{"the", "company", "ceo"}},
+		{"typographic possessive", "en", "Alice’s book", []string{"alice", "book"}},
+		{"contraction kept", "en", "can't stop", []string{"can't", "stop"}},
+		{"possessive left alone in french", "fr", "Chanel's", []string{"chanel's"}},
+		{"mixed latin and cjk", "zh", "使用Go语言开发API", []string{"使用", "go", "语言", "言开", "开发", "api"}},
+		{"mixed latin and cyrillic", "en", "Moscow (Москва) is big", []string{"moscow", "москва", "is", "big"}},
+		{"numbers", "en", "3.14 and 1,000", []string{"3.14", "and", "1,000"}},
+		{"case folding", "de", "STRASSE Straße", []string{"strasse", "strasse"}},
+		{"decomposed accents", "fr", "caf\u00e9 cafe\u0301", []string{"caf\u00e9", "caf\u00e9"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Tokenize(tt.text, tt.lang); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize(%q, %q) = %q, want %q", tt.text, tt.lang, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenizeSpans(t *testing.T) {
+	tests := []struct {
+		name string
+		lang string
+		text string
+		want []string // The source text of each token
+	}{
+		{"elision offsets skip the article", "fr", "l'homme d’affaires", []string{"homme", "affaires"}},
+		{"possessive offsets end at the word", "en", "Alice's cat", []string{"Alice", "cat"}},
+		{"bigram offsets", "zh", "我爱北京", []string{"我爱", "爱北", "北京"}},
+		{"offsets in runes after multibyte text", "zh", "北京 Go", []string{"北京", "Go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runes := []rune(tt.text)
+			var got []string
+			for _, tok := range TokenizeSpans(tt.text, tt.lang) {
+				got = append(got, string(runes[tok.Start:tok.End]))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TokenizeSpans(%q, %q) covers %q, want %q", tt.text, tt.lang, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWordSpans(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"punctuation dropped", "Hello, world!", []string{"Hello", "world"}},
+		{"mid-word punctuation", "S:t Erik's e.g.", []string{"S:t", "Erik's", "e.g"}},
+		{"katakana word", "コーヒーを", []string{"コーヒー", "を"}},
+		{"ideographs break on both sides", "漢字", []string{"漢", "字"}},
+		{"thai run is one word", "ภาษาไทย ok", []string{"ภาษาไทย", "ok"}},
+		{"combining marks stay attached", "naïve", []string{"naïve"}},
+		{"underscore joins", "snake_case", []string{"snake_case"}},
+		{"hebrew gershayim", `צה"ל`, []string{`צה"ל`}},
+		{"mixed scripts without spaces", "abcабв", []string{"abcабв"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wordTokens(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wordSpans(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			for _, w := range wordTokens(tt.text) {
+				if !utf8.ValidString(w) {
+					t.Errorf("wordSpans(%q) split a character: %q", tt.text, w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package analyzer
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Sentence is a sentence of the source text with rune offsets (end exclusive)
+type Sentence struct {
+	Text  string `json:"text"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// sentenceBoundary matches terminal punctuation (plus closing quotes/brackets)
+// followed by whitespace, CJK full stops, and blank lines between paragraphs
+var sentenceBoundary = regexp.MustCompile(`[.!?]+["'”’)\]]*\s+|[。！？]+|\n\s*\n`)
+
+// SplitSentences splits text into trimmed sentences, keeping their offsets
+func SplitSentences(text string) []Sentence {
+	toRune := runeOffsets(text)
+	var out []Sentence
+
+	emit := func(start, end int) {
+		seg := text[start:end]
+		trimmedLeft := strings.TrimLeft(seg, " \t\r\n")
+		start += len(seg) - len(trimmedLeft)
+		seg = strings.TrimRight(trimmedLeft, " \t\r\n")
+		if seg == "" {
+			return
+		}
+		out = append(out, Sentence{Text: seg, Start: toRune[start], End: toRune[start+len(seg)]})
+	}
+
+	prev := 0
+	for _, m := range sentenceBoundary.FindAllStringIndex(text, -1) {
+		if isAbbreviation(text[:m[0]], text[m[0]:m[1]], text[m[1]:]) {
+			continue
+		}
+		emit(prev, m[1])
+		prev = m[1]
+	}
+	emit(prev, len(text))
+	return out
+}
+
+// isAbbreviation reports whether a single period ending before is part of a word
+// rather than the end of a sentence: a title or Latin abbreviation ("Dr.", "e.g."),
+// an initial ("J. Smith"), or any period followed by a lowercase word or a digit
+// Ambiguous abbreviations ("etc.", "Inc.") end a sentence when a capital follows
+func isAbbreviation(before, punct, after string) bool {
+	if strings.TrimRight(punct, "\"'”’)] \t\r\n") != "." {
+		return false
+	}
+	start := len(before)
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(before[:start])
+		if !unicode.IsLetter(r) && r != '.' {
+			break
+		}
+		start -= size
+	}
+	word := strings.Trim(before[start:], ".")
+	if word == "" {
+		return false
+	}
+
+	if next, _ := utf8.DecodeRuneInString(after); unicode.IsLower(next) || unicode.IsDigit(next) {
+		return true
+	}
+	if first, _ := utf8.DecodeRuneInString(word); utf8.RuneCountInString(word) == 1 && unicode.IsUpper(first) {
+		return true
+	}
+	return abbreviations[strings.ToLower(word)]
+}
+
+// abbreviations are never sentence-final in practice: titles precede a name and
+// Latin forms precede an example
+var abbreviations = setOf(
+	"mr", "mrs", "ms", "dr", "prof", "sr", "jr", "st", "mt", "gen", "col", "lt", "sgt", "capt", "rev", "hon",
+	"vs", "e.g", "i.e", "cf", "viz", "approx", "ca", "fig", "figs", "eq", "vol", "pp", "ch", "sec",
+	"u.s", "u.k", "u.n", "e.u", "jan", "feb", "mar", "apr", "jun", "jul", "aug", "sep", "sept", "oct", "nov", "dec",
+	"dept", "est", "ref")
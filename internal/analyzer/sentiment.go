@@ -0,0 +1,232 @@
+package analyzer
+
+import (
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+)
+
+// Sentiment labels, as returned by the LLM
+const (
+	SentimentPositive = "positive"
+	SentimentNeutral  = "neutral"
+	SentimentNegative = "negative"
+)
+
+const (
+	// sentimentThreshold is the compound score beyond which text is positive or negative
+	sentimentThreshold = 0.05
+	// conflictThreshold is the compound score that contradicts an opposite label
+	conflictThreshold = 0.5
+
+	// Rule weights from VADER (Hutto & Gilbert, 2014)
+	boosterIncrement = 0.293 // Intensifiers ("very") and dampeners ("slightly")
+	capsIncrement    = 0.733 // SHOUTED words in otherwise mixed-case text
+	negationScalar   = -0.74 // "not good" is weaker than "bad"
+	exclamationBoost = 0.292 // Per "!", up to four
+	questionBoost    = 0.18  // Per "?" beyond the first, up to 0.96
+	normalization    = 15    // Alpha in compound = sum / sqrt(sum^2 + alpha)
+)
+
+// IsSentimentLabel reports whether label is one of the three sentiment classes
+func IsSentimentLabel(label string) bool {
+	switch label {
+	case SentimentPositive, SentimentNeutral, SentimentNegative:
+		return true
+	}
+	return false
+}
+
+// ScoreSentiment rates text with a valence lexicon and rules for negation,
+// intensifiers, contrast ("but"), capitalisation and punctuation emphasis
+// Algorithm: score each sentence VADER-style -> average compound and class shares
+// over sentences that carry sentiment
+// Only English has a lexicon; ok is false for other languages (undetermined text is tried as English)
+func ScoreSentiment(text, lang string) (scores models.SentimentScores, ok bool) {
+	if lang != "en" && lang != LanguageUndetermined && lang != "" {
+		return models.SentimentScores{}, false
+	}
+
+	var n float64
+	for _, s := range SplitSentences(text) {
+		sc, hasSentiment := scoreSentence(s.Text)
+		if !hasSentiment {
+			continue
+		}
+		scores.Compound += sc.Compound
+		scores.Positive += sc.Positive
+		scores.Neutral += sc.Neutral
+		scores.Negative += sc.Negative
+		n++
+	}
+	if n == 0 {
+		scores.Neutral = 1
+	} else {
+		scores.Compound = round3(scores.Compound / n)
+		scores.Positive = round3(scores.Positive / n)
+		scores.Neutral = round3(scores.Neutral / n)
+		scores.Negative = round3(scores.Negative / n)
+	}
+	scores.Label = SentimentLabel(scores.Compound)
+	return scores, true
+}
+
+// SentimentLabel maps a compound score onto a class
+func SentimentLabel(compound float64) string {
+	switch {
+	case compound >= sentimentThreshold:
+		return SentimentPositive
+	case compound <= -sentimentThreshold:
+		return SentimentNegative
+	}
+	return SentimentNeutral
+}
+
+// SentimentConflict reports whether label contradicts clearly polar scores
+// (a "positive" label on strongly negative text, or the reverse)
+func SentimentConflict(label string, scores models.SentimentScores) bool {
+	switch label {
+	case SentimentPositive:
+		return scores.Compound <= -conflictThreshold
+	case SentimentNegative:
+		return scores.Compound >= conflictThreshold
+	}
+	return false
+}
+
+// scoreSentence applies the VADER rules to one sentence; ok is false when no word carries sentiment
+func scoreSentence(sentence string) (models.SentimentScores, bool) {
+	words := wordTokens(sentence)
+	fold := newFolder()
+	lower := make([]string, len(words))
+	for i, w := range words {
+		lower[i] = fold(w)
+	}
+	mixedCase := hasMixedCase(words)
+
+	valences := make([]float64, len(words))
+	for i, w := range lower {
+		v, ok := sentimentLexicon[w]
+		if !ok || boosters[w] != 0 {
+			continue
+		}
+		if i+1 < len(lower) && idiomFollowers[w+" "+lower[i+1]] {
+			continue // "kind of", "no doubt" are not sentiment
+		}
+
+		// Emphasis: SHOUTING and intensifiers in the three preceding words
+		if mixedCase && isUpper(words[i]) {
+			v += math.Copysign(capsIncrement, v)
+		}
+		for back, decay := 1, 1.0; back <= 3 && i-back >= 0; back, decay = back+1, decay-0.05 {
+			if b := boosters[lower[i-back]]; b != 0 {
+				scalar := b * decay
+				if mixedCase && isUpper(words[i-back]) {
+					scalar += math.Copysign(capsIncrement, b) * decay
+				}
+				v += math.Copysign(scalar, v)
+			}
+		}
+		// Negation in the three preceding words flips and softens
+		for back := 1; back <= 3 && i-back >= 0; back++ {
+			if isNegation(lower[i-back]) {
+				v *= negationScalar
+				break
+			}
+		}
+		valences[i] = v
+	}
+
+	// Contrast: "but" shifts weight onto the clause that follows
+	for i, w := range lower {
+		if w != "but" {
+			continue
+		}
+		for j := range valences {
+			if j < i {
+				valences[j] *= 0.5
+			} else if j > i {
+				valences[j] *= 1.5
+			}
+		}
+		break
+	}
+
+	var sum, pos, neg, neu float64
+	for _, v := range valences {
+		sum += v
+		switch {
+		case v > 0:
+			pos += v + 1 // +1 counts the word itself, as VADER does
+		case v < 0:
+			neg += v - 1
+		default:
+			neu++
+		}
+	}
+	if pos == 0 && neg == 0 {
+		return models.SentimentScores{}, false
+	}
+
+	// Punctuation emphasis amplifies whichever direction the sentence already has
+	emphasis := punctuationEmphasis(sentence)
+	if sum > 0 {
+		sum += emphasis
+		pos += emphasis
+	} else if sum < 0 {
+		sum -= emphasis
+		neg -= emphasis
+	}
+
+	total := pos - neg + neu
+	return models.SentimentScores{
+		Compound: sum / math.Sqrt(sum*sum+normalization),
+		Positive: pos / total,
+		Neutral:  neu / total,
+		Negative: -neg / total,
+	}, true
+}
+
+// punctuationEmphasis scores "!" and repeated "?" as intensity
+func punctuationEmphasis(sentence string) float64 {
+	exclamations := math.Min(float64(strings.Count(sentence, "!")), 4)
+	emphasis := exclamations * exclamationBoost
+	if q := strings.Count(sentence, "?"); q > 1 {
+		emphasis += math.Min(float64(q)*questionBoost, 0.96)
+	}
+	return emphasis
+}
+
+func isNegation(w string) bool {
+	return negations[w] || strings.HasSuffix(w, "n't")
+}
+
+func isUpper(w string) bool {
+	letters := 0
+	for _, r := range w {
+		if unicode.IsLower(r) {
+			return false
+		}
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	return letters > 1
+}
+
+// hasMixedCase is false when the whole sentence is shouted, so capitals carry no emphasis
+func hasMixedCase(words []string) bool {
+	upper := 0
+	for _, w := range words {
+		if isUpper(w) {
+			upper++
+		}
+	}
+	return upper > 0 && upper < len(words)
+}
+
+func round3(x float64) float64 {
+	return math.Round(x*1000) / 1000
+}
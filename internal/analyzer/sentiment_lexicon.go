@@ -0,0 +1,85 @@
+package analyzer
+
+// sentimentLexicon maps English words to a valence from -4 (most negative) to 4
+// (most positive), on the scale of the VADER lexicon; inflections are listed explicitly
+var sentimentLexicon = map[string]float64{
+	// Positive
+	"good": 1.9, "great": 3.1, "excellent": 3.2, "amazing": 2.8, "awesome": 3.1, "fantastic": 2.6,
+	"wonderful": 2.7, "outstanding": 3.0, "superb": 3.1, "brilliant": 2.8, "perfect": 2.7, "best": 3.2,
+	"better": 1.9, "nice": 1.8, "fine": 0.8, "pleasant": 2.3, "positive": 2.6, "happy": 2.7,
+	"happier": 2.4, "happiest": 3.2, "glad": 2.0, "pleased": 1.9, "delighted": 3.2, "joy": 2.8,
+	"love": 3.2, "loved": 2.9, "loves": 2.7, "loving": 2.9, "liked": 1.8, "likes": 1.8,
+	"enjoy": 2.2, "enjoyed": 2.3, "enjoying": 2.4, "enjoyable": 1.9, "beautiful": 2.9, "impressive": 2.3,
+	"impressed": 2.1, "success": 2.7, "successful": 2.8, "succeed": 2.2, "succeeded": 1.8, "win": 2.8,
+	"wins": 2.7, "won": 2.7, "winning": 2.4, "benefit": 2.0, "benefits": 1.6, "beneficial": 1.9,
+	"improve": 1.9, "improved": 2.1, "improvement": 2.0, "improves": 1.8, "gain": 2.4, "gains": 1.8,
+	"growth": 1.6, "strong": 2.3, "stronger": 1.6, "robust": 1.4, "efficient": 1.8, "effective": 2.1,
+	"helpful": 1.8, "help": 1.7, "helps": 1.6, "useful": 1.9, "valuable": 2.1, "reliable": 1.9,
+	"easy": 1.9, "easier": 1.8, "fast": 1.1, "smooth": 1.2, "clean": 1.7, "safe": 1.9, "secure": 1.4,
+	"recommend": 1.5, "recommended": 0.8, "favorite": 2.0, "favourite": 2.0, "exciting": 2.2,
+	"excited": 1.4, "thrilled": 1.9, "proud": 2.1, "grateful": 2.0, "thanks": 1.9, "thank": 1.5,
+	"appreciate": 1.7, "appreciated": 2.3, "hope": 1.9, "hopeful": 1.6, "optimistic": 1.3,
+	"confident": 2.2, "comfortable": 1.5, "calm": 1.3, "friendly": 2.2, "kind": 2.4, "fair": 1.3,
+	"fun": 2.3, "funny": 1.9, "cool": 1.3, "clever": 1.2, "smart": 1.7, "innovative": 1.9,
+	"solid": 0.9, "affordable": 1.3, "cheap": 0.5, "popular": 1.8, "praise": 2.6, "praised": 2.2,
+	"welcome": 2.0, "celebrate": 2.7, "celebrated": 2.7, "boost": 1.7, "boosted": 1.5,
+	"rise": 0.9, "rose": 0.8, "soared": 1.5, "profit": 1.9, "profits": 1.6, "profitable": 1.9,
+	"satisfied": 1.8, "satisfying": 2.0, "incredible": 2.0, "remarkable": 2.2, "ok": 1.2, "okay": 0.9,
+	"agree": 1.5, "support": 1.7, "supported": 1.3, "trust": 2.3, "peace": 2.5,
+
+	// Negative
+	"bad": -2.5, "worse": -2.1, "worst": -3.1, "terrible": -2.1, "horrible": -2.5, "awful": -2.0,
+	"poor": -2.1, "poorly": -1.8, "disappointing": -2.2, "disappointed": -1.9, "disappointment": -2.3,
+	"hate": -2.7, "hated": -3.2, "hates": -1.9, "dislike": -1.6, "sad": -2.1, "unhappy": -1.8,
+	"angry": -2.3, "annoyed": -1.6, "annoying": -1.7, "frustrated": -2.4, "frustrating": -1.9,
+	"upset": -1.6, "fear": -2.2, "afraid": -2.0, "scared": -1.9, "worried": -1.2, "worry": -1.9,
+	"concern": -0.7, "concerns": -0.9, "concerned": -1.3, "problem": -1.7, "problems": -1.7,
+	"issue": -0.6, "issues": -0.7, "fail": -2.5, "failed": -2.3, "fails": -1.8, "failure": -2.3,
+	"failing": -2.2, "error": -1.7, "errors": -1.4, "bug": -1.1, "bugs": -1.1, "broken": -2.1,
+	"crash": -1.7, "crashed": -1.6, "crashes": -1.6, "slow": -1.0, "slower": -1.1, "expensive": -0.9,
+	"difficult": -1.5, "hard": -0.4, "painful": -1.9, "pain": -2.3, "hurt": -2.4, "harm": -2.5,
+	"harmful": -2.6, "damage": -2.2, "damaged": -1.9, "dangerous": -2.1, "danger": -2.4, "risk": -1.1,
+	"risky": -0.8, "threat": -2.4, "loss": -1.3, "losses": -1.7, "lose": -1.7, "lost": -1.3,
+	"decline": -1.1, "declined": -0.5, "fell": -0.6, "drop": -1.1, "dropped": -1.1, "plunged": -1.6,
+	"weak": -1.9, "weaker": -1.5, "crisis": -3.1, "disaster": -3.1, "catastrophe": -3.4,
+	"wrong": -2.1, "mistake": -1.4, "mistakes": -1.5, "useless": -1.8, "waste": -1.8, "wasted": -2.2,
+	"ugly": -2.3, "boring": -1.3, "bored": -1.1, "confusing": -1.3, "confused": -1.3, "unfair": -2.1,
+	"rude": -2.0, "stupid": -2.4, "ridiculous": -1.5, "nasty": -2.6, "toxic": -2.9, "dirty": -1.9,
+	"unreliable": -1.9, "unsafe": -2.2, "complaint": -1.5, "complaints": -1.7, "complain": -1.7,
+	"blame": -1.4, "blamed": -2.1, "criticism": -1.9, "criticized": -1.7, "attack": -2.1,
+	"attacked": -2.0, "war": -2.9, "kill": -3.7, "killed": -3.5, "death": -2.9, "died": -2.6,
+	"dead": -3.3, "sick": -2.3, "ill": -1.8, "disease": -2.1, "violence": -3.1, "fraud": -2.8,
+	"scam": -2.7, "corrupt": -3.0, "lawsuit": -1.4, "delay": -1.3, "delayed": -0.9, "delays": -1.1,
+	"cancel": -1.0, "cancelled": -1.0, "refund": -0.4, "layoffs": -2.0, "unemployment": -1.9,
+	"recession": -2.2, "shortage": -1.7, "struggle": -1.8, "struggling": -1.7, "miss": -0.6,
+	"missed": -1.2, "sorry": -0.3, "disagree": -1.6, "reject": -1.7, "rejected": -1.8,
+	"doubt": -1.5, "lack": -1.2, "lacks": -1.2, "lacking": -1.4, "mediocre": -1.0, "meh": -0.3,
+}
+
+// boosters intensify (positive) or dampen (negative) the sentiment word that follows
+var boosters = map[string]float64{
+	"absolutely": boosterIncrement, "amazingly": boosterIncrement, "completely": boosterIncrement,
+	"deeply": boosterIncrement, "enormously": boosterIncrement, "entirely": boosterIncrement,
+	"especially": boosterIncrement, "exceptionally": boosterIncrement, "extremely": boosterIncrement,
+	"greatly": boosterIncrement, "highly": boosterIncrement, "hugely": boosterIncrement,
+	"incredibly": boosterIncrement, "particularly": boosterIncrement, "really": boosterIncrement,
+	"remarkably": boosterIncrement, "so": boosterIncrement, "substantially": boosterIncrement,
+	"thoroughly": boosterIncrement, "totally": boosterIncrement, "truly": boosterIncrement,
+	"tremendously": boosterIncrement, "unbelievably": boosterIncrement, "utterly": boosterIncrement,
+	"very": boosterIncrement, "most": boosterIncrement, "more": boosterIncrement, "super": boosterIncrement,
+
+	"almost": -boosterIncrement, "barely": -boosterIncrement, "hardly": -boosterIncrement,
+	"slightly": -boosterIncrement, "somewhat": -boosterIncrement, "marginally": -boosterIncrement,
+	"occasionally": -boosterIncrement, "partly": -boosterIncrement, "scarcely": -boosterIncrement,
+	"less": -boosterIncrement, "little": -boosterIncrement, "rather": -boosterIncrement,
+	"fairly": -boosterIncrement, "quite": -boosterIncrement,
+}
+
+// negations flip the sentiment of the following words ("n't" forms are matched by suffix)
+var negations = setOf(
+	"not", "no", "never", "none", "nobody", "nothing", "neither", "nor", "nowhere", "without",
+	"cannot", "cant", "dont", "doesnt", "didnt", "isnt", "wasnt", "arent", "werent", "wont",
+	"wouldnt", "shouldnt", "couldnt", "havent", "hasnt", "hadnt", "aint", "rarely", "seldom")
+
+// idiomFollowers are sentiment words that lose their meaning in a fixed phrase
+var idiomFollowers = setOf("kind of", "fine print", "fair share")
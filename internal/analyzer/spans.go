@@ -0,0 +1,92 @@
+package analyzer
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LocateQuote finds quote in text and returns its rune offsets (end exclusive)
+// Matching tries exact text first, then ignores case and collapses whitespace,
+// so quotes copied by an LLM with slightly different spacing still resolve
+func LocateQuote(text, quote string) (start, end int, ok bool) {
+	quote = strings.TrimSpace(quote)
+	if quote == "" {
+		return 0, 0, false
+	}
+
+	var loc []int
+	if i := strings.Index(text, quote); i >= 0 {
+		loc = []int{i, i + len(quote)}
+	} else {
+		parts := strings.Fields(quote)
+		for i, p := range parts {
+			parts[i] = regexp.QuoteMeta(p)
+		}
+		loc = regexp.MustCompile(`(?i)` + strings.Join(parts, `\s+`)).FindStringIndex(text)
+	}
+	if loc == nil {
+		return 0, 0, false
+	}
+
+	toRune := runeOffsets(text)
+	return toRune[loc[0]], toRune[loc[1]], true
+}
+
+// runeOffsets maps every byte offset in text (including len(text)) to a rune offset
+func runeOffsets(text string) []int {
+	offsets := make([]int, len(text)+1)
+	n := 0
+	for i := range text {
+		offsets[i] = n
+		n++
+	}
+	for i := 1; i < len(text); i++ {
+		if !utf8.RuneStart(text[i]) {
+			offsets[i] = offsets[i-1]
+		}
+	}
+	offsets[len(text)] = n
+	return offsets
+}
+
+// findWord returns the byte spans of whole-word occurrences of needle,
+// preferring exact case and falling back to a case-insensitive match
+func findWord(text, needle string) [][2]int {
+	if needle == "" {
+		return nil
+	}
+	var spans [][2]int
+	for _, caseless := range []bool{false, true} {
+		re := regexp.QuoteMeta(needle)
+		if caseless {
+			re = "(?i)" + re
+		}
+		for _, m := range regexp.MustCompile(re).FindAllStringIndex(text, -1) {
+			if isWordBoundary(text, m[0], m[1]) {
+				spans = append(spans, [2]int{m[0], m[1]})
+			}
+		}
+		if len(spans) > 0 {
+			break
+		}
+	}
+	return spans
+}
+
+func isWordBoundary(text string, start, end int) bool {
+	if start > 0 {
+		r, _ := utf8.DecodeLastRuneInString(text[:start])
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	if end < len(text) {
+		r, _ := utf8.DecodeRuneInString(text[end:])
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
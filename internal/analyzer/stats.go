@@ -0,0 +1,107 @@
+package analyzer
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+)
+
+// wordsPerMinute is the average adult silent reading rate for non-fiction (Brysbaert, 2019)
+const wordsPerMinute = 238
+
+// paragraphBreak separates paragraphs: a blank line, possibly holding whitespace
+var paragraphBreak = regexp.MustCompile(`\n[ \t\r]*\n`)
+
+// MeasureText computes document statistics for text in lang
+// Words follow the Unicode word boundary rules; Chinese and Japanese characters count individually,
+// as they are not separated by spaces. Flesch reading ease and Flesch-Kincaid grade
+// use vowel-group syllable counts and are only computed for English (or undetermined) text
+func MeasureText(text, lang string) models.TextStats {
+	var stats models.TextStats
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			stats.Characters++
+		}
+	}
+	for _, p := range paragraphBreak.Split(text, -1) {
+		if strings.TrimSpace(p) != "" {
+			stats.Paragraphs++
+		}
+	}
+
+	fold := newFolder()
+	var words []string
+	for _, w := range wordTokens(text) {
+		if isCJK(w) {
+			for _, r := range w {
+				words = append(words, string(r))
+			}
+			continue
+		}
+		words = append(words, fold(w))
+	}
+	stats.Words = len(words)
+	if stats.Words == 0 {
+		return stats
+	}
+	stats.Sentences = len(SplitSentences(text))
+	if stats.Sentences == 0 {
+		stats.Sentences = 1
+	}
+
+	distinct := make(map[string]bool, len(words))
+	for _, w := range words {
+		distinct[w] = true
+	}
+	wordsPerSentence := float64(stats.Words) / float64(stats.Sentences)
+	stats.AvgSentenceLength = round2(wordsPerSentence)
+	stats.TypeTokenRatio = round3(float64(len(distinct)) / float64(stats.Words))
+	stats.ReadingTimeSeconds = int(math.Ceil(float64(stats.Words) * 60 / wordsPerMinute))
+
+	if lang == "en" || lang == LanguageUndetermined || lang == "" {
+		syllables := 0
+		for _, w := range words {
+			syllables += countSyllables(w)
+		}
+		syllablesPerWord := float64(syllables) / float64(stats.Words)
+		ease := round2(206.835 - 1.015*wordsPerSentence - 84.6*syllablesPerWord)
+		grade := round2(0.39*wordsPerSentence + 11.8*syllablesPerWord - 15.59)
+		stats.ReadingEase, stats.GradeLevel = &ease, &grade
+	}
+	return stats
+}
+
+// countSyllables estimates the syllables of a lowercase English word as its vowel groups,
+// less a silent final "e" ("make") or "ed" ("jumped"); numbers and other words have at least one
+func countSyllables(word string) int {
+	word = strings.ReplaceAll(word, "'", "")
+	groups, prevVowel := 0, false
+	for _, r := range word {
+		vowel := strings.ContainsRune("aeiouy", r)
+		if vowel && !prevVowel {
+			groups++
+		}
+		prevVowel = vowel
+	}
+	n := utf8.RuneCountInString(word)
+	if groups > 1 && n > 3 {
+		switch {
+		case strings.HasSuffix(word, "e") && !strings.HasSuffix(word, "le") && !strings.HasSuffix(word, "ee"):
+			groups--
+		case strings.HasSuffix(word, "ed") && !strings.HasSuffix(word, "ted") && !strings.HasSuffix(word, "ded"):
+			groups--
+		}
+	}
+	if groups == 0 {
+		return 1
+	}
+	return groups
+}
+
+func round2(x float64) float64 {
+	return math.Round(x*100) / 100
+}
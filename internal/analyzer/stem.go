@@ -0,0 +1,278 @@
+package analyzer
+
+// Stem reduces a lowercase word to its stem for lang
+// English uses the Porter (1980) algorithm; other languages are returned
+// unchanged, since an English stemmer would mangle them
+// Stems group inflections ("connected", "connection" -> "connect") but are not
+// always words, so show a surface form to users rather than the stem itself
+func Stem(word, lang string) string {
+	if lang != "en" || len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word // Porter is defined over a-z only
+		}
+	}
+	p := &porter{b: []byte(word), k: len(word) - 1}
+	p.step1ab()
+	p.step1c()
+	p.step2()
+	p.step3()
+	p.step4()
+	p.step5()
+	return string(p.b[:p.k+1])
+}
+
+// porter holds the word being stemmed: b[0..k] is the current word and
+// j marks the end of the stem after a successful ends() match
+type porter struct {
+	b    []byte
+	k, j int
+}
+
+// cons reports whether b[i] is a consonant; y is a consonant after a vowel or at the start
+func (p *porter) cons(i int) bool {
+	switch p.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !p.cons(i-1)
+	}
+	return true
+}
+
+// m measures the number of consonant-vowel sequences in b[0..j]: [C](VC)^m[V]
+func (p *porter) m() int {
+	n, i := 0, 0
+	for ; i <= p.j && p.cons(i); i++ {
+	}
+	for {
+		for ; i <= p.j && !p.cons(i); i++ {
+		}
+		if i > p.j {
+			return n
+		}
+		for ; i <= p.j && p.cons(i); i++ {
+		}
+		n++
+		if i > p.j {
+			return n
+		}
+	}
+}
+
+func (p *porter) vowelInStem() bool {
+	for i := 0; i <= p.j; i++ {
+		if !p.cons(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// doublec reports whether b[i-1..i] is a double consonant
+func (p *porter) doublec(i int) bool {
+	return i >= 1 && p.b[i] == p.b[i-1] && p.cons(i)
+}
+
+// cvc reports whether b[i-2..i] is consonant-vowel-consonant with the last not w, x or y
+// ("hop" -> "hope", but not "snow" -> "snowe")
+func (p *porter) cvc(i int) bool {
+	if i < 2 || !p.cons(i) || p.cons(i-1) || !p.cons(i-2) {
+		return false
+	}
+	switch p.b[i] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+// ends reports whether b[0..k] ends with s, setting j to the end of the stem
+func (p *porter) ends(s string) bool {
+	n := len(s)
+	if n > p.k+1 || string(p.b[p.k-n+1:p.k+1]) != s {
+		return false
+	}
+	p.j = p.k - n
+	return true
+}
+
+// setTo replaces b[j+1..k] with s
+func (p *porter) setTo(s string) {
+	p.b = append(p.b[:p.j+1], s...)
+	p.k = p.j + len(s)
+}
+
+// replace applies setTo when the stem has at least one VC sequence
+func (p *porter) replace(s string) {
+	if p.m() > 0 {
+		p.setTo(s)
+	}
+}
+
+// rules applies the first matching suffix rule; later rules are not tried after a match
+func (p *porter) rules(pairs ...string) {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if p.ends(pairs[i]) {
+			p.replace(pairs[i+1])
+			return
+		}
+	}
+}
+
+// step1ab removes plurals and -ed/-ing
+func (p *porter) step1ab() {
+	if p.b[p.k] == 's' {
+		switch {
+		case p.ends("sses"):
+			p.k -= 2
+		case p.ends("ies"):
+			p.setTo("i")
+		case p.b[p.k-1] != 's':
+			p.k--
+		}
+	}
+	if p.ends("eed") {
+		if p.m() > 0 {
+			p.k--
+		}
+		return
+	}
+	if (p.ends("ed") || p.ends("ing")) && p.vowelInStem() {
+		p.k = p.j
+		switch {
+		case p.ends("at"):
+			p.setTo("ate")
+		case p.ends("bl"):
+			p.setTo("ble")
+		case p.ends("iz"):
+			p.setTo("ize")
+		case p.doublec(p.k):
+			switch p.b[p.k] {
+			case 'l', 's', 'z':
+			default:
+				p.k--
+			}
+		default:
+			p.j = p.k
+			if p.m() == 1 && p.cvc(p.k) {
+				p.setTo("e")
+			}
+		}
+	}
+}
+
+// step1c turns terminal y into i when there is another vowel in the stem
+func (p *porter) step1c() {
+	if p.ends("y") && p.vowelInStem() {
+		p.b = append(p.b[:p.k], 'i')
+	}
+}
+
+// step2 maps double suffixes to single ones ("-ization" -> "-ize")
+func (p *porter) step2() {
+	if p.k < 1 {
+		return
+	}
+	switch p.b[p.k-1] {
+	case 'a':
+		p.rules("ational", "ate", "tional", "tion")
+	case 'c':
+		p.rules("enci", "ence", "anci", "ance")
+	case 'e':
+		p.rules("izer", "ize")
+	case 'l':
+		p.rules("bli", "ble", "alli", "al", "entli", "ent", "eli", "e", "ousli", "ous")
+	case 'o':
+		p.rules("ization", "ize", "ation", "ate", "ator", "ate")
+	case 's':
+		p.rules("alism", "al", "iveness", "ive", "fulness", "ful", "ousness", "ous")
+	case 't':
+		p.rules("aliti", "al", "iviti", "ive", "biliti", "ble")
+	case 'g':
+		p.rules("logi", "log")
+	}
+}
+
+// step3 handles -ic-, -full, -ness etc.
+func (p *porter) step3() {
+	switch p.b[p.k] {
+	case 'e':
+		p.rules("icate", "ic", "ative", "", "alize", "al")
+	case 'i':
+		p.rules("iciti", "ic")
+	case 'l':
+		p.rules("ical", "ic", "ful", "")
+	case 's':
+		p.rules("ness", "")
+	}
+}
+
+// step4 removes -ant, -ence etc. from stems with m > 1
+func (p *porter) step4() {
+	if p.k < 1 {
+		return
+	}
+	var suffixes []string
+	switch p.b[p.k-1] {
+	case 'a':
+		suffixes = []string{"al"}
+	case 'c':
+		suffixes = []string{"ance", "ence"}
+	case 'e':
+		suffixes = []string{"er"}
+	case 'i':
+		suffixes = []string{"ic"}
+	case 'l':
+		suffixes = []string{"able", "ible"}
+	case 'n':
+		suffixes = []string{"ant", "ement", "ment", "ent"}
+	case 'o':
+		if p.ends("ion") && p.j >= 0 && (p.b[p.j] == 's' || p.b[p.j] == 't') {
+			break
+		}
+		suffixes = []string{"ou"}
+	case 's':
+		suffixes = []string{"ism"}
+	case 't':
+		suffixes = []string{"ate", "iti"}
+	case 'u':
+		suffixes = []string{"ous"}
+	case 'v':
+		suffixes = []string{"ive"}
+	case 'z':
+		suffixes = []string{"ize"}
+	default:
+		return
+	}
+	if suffixes != nil {
+		matched := false
+		for _, s := range suffixes {
+			if p.ends(s) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return
+		}
+	}
+	if p.m() > 1 {
+		p.k = p.j
+	}
+}
+
+// step5 removes a final -e and reduces -ll when m > 1
+func (p *porter) step5() {
+	p.j = p.k
+	if p.b[p.k] == 'e' {
+		if a := p.m(); a > 1 || a == 1 && !p.cvc(p.k-1) {
+			p.k--
+		}
+	}
+	if p.b[p.k] == 'l' && p.doublec(p.k) && p.m() > 1 {
+		p.k--
+	}
+}
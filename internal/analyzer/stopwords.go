@@ -0,0 +1,63 @@
+package analyzer
+
+import "strings"
+
+// Stopwords holds the built-in stopword list for each supported language (ISO 639-1)
+// Used both for keyword filtering and as the evidence base for language detection
+var Stopwords = map[string]map[string]bool{
+	"en": wordSet(`a about above after again against all also am an and any are as at be because been
+		before being below between both but by can could did do does doing don down during each few for
+		from further had has have having he her here hers herself him himself his how i if in into is it
+		its itself just me more most my myself no nor not now of off on once only or other our ours
+		ourselves out over own same she should so some such than that the their theirs them themselves
+		then there these they this those through to too under until up very was we were what when where
+		which while who whom why will with would you your yours yourself yourselves s t can't won't isn't
+		it's i'm we're they're you're that's there's`),
+	"es": wordSet(`a al algo algunas algunos ante antes como con contra cual cuando de del desde donde durante
+		e el ella ellas ellos en entre era eres es esa esas ese eso esos esta estaba estado estamos estan
+		estar estas este esto estos fue fueron ha habia han hasta hay la las le les lo los mas me mi mis
+		mucho muy más nada ni no nos nosotros o os otra otro para pero poco por porque que quien se ser
+		si sin sobre son su sus también tambien te tiene tienen todo todos tu tus un una uno unos y ya yo
+		él está están qué`),
+	"fr": wordSet(`a ai au aux avec avons avez ce ces cette comme dans de des du elle elles en est et été être
+		eu il ils je la le les leur leurs lui ma mais me même mes moi mon ne nos notre nous on ont ou où
+		par pas pour qu que qui sa se ses son sont sur ta te tes toi ton tu un une vos votre vous y c d
+		j l m n s t plus très était aussi tout tous cela ça cet vont sera sont ont fait`),
+	"de": wordSet(`aber alle allem allen aller als also am an auch auf aus bei bin bis bist da damit dann das
+		dass dein deine dem den der des dich die dir doch dort du durch ein eine einem einen einer eines
+		er es euch euer für hat hatte haben hier ich ihm ihn ihr ihre im in ist ja jede jedem jeden jeder
+		kann kein keine mich mir mit muss nach nicht noch nun nur ob oder ohne sehr sein seine sich sie
+		sind so über um und uns unser unter vom von vor war waren was weil wenn werden wie wir wird wo zu
+		zum zur`),
+	"it": wordSet(`a ad al alla alle anche che chi ci come con cui da dal dalla dei del della delle di dove e
+		è ed era essere gli ha hanno i il in io la le lei lo loro lui ma mi mio nel nella nelle noi non o
+		per più perché poi quale quando quello questa questo se si sia sono su sua suo sul sulla tra un
+		una uno voi l d c sei stato molto`),
+	"pt": wordSet(`a ao aos as até com como da das de dela dele deles do dos e ela elas ele eles em entre era
+		essa esse esta este eu foi for foram há isso isto já lhe mais mas me mesmo meu minha muito na nas
+		não nem no nos nós num numa o os ou para pela pelas pelo pelos por qual quando que quem se sem
+		ser seu sua são só também te tem tu um uma você vocês à é está estão`),
+	"nl": wordSet(`aan al als bij dan dat de der deze die dit doch door dus een en er ge geen had heb hebben
+		heeft hem het hier hij hoe hun ik in is ja je kan kon maar me meer men met mij mijn na naar niet
+		niets nog nu of om omdat ons ook op over te tegen toch toen tot u uit van veel voor want was wat
+		we wel werd wie wij wil worden zal ze zelf zich zij zijn zo zonder zou`),
+	"ru": wordSet(`и в во не что он на я с со как а то все она так его но да ты к у же вы за бы по только
+		ее мне было вот от меня еще нет о из ему теперь когда даже ну ли если уже или ни быть был него до
+		вас там потом себя ей может они тут где есть надо ней для мы тебя их чем была сам без чего раз
+		тоже себе под будет тогда кто этот того потому этого какой здесь этом один мой тем чтобы при об
+		после над больше тот через эти нас про всего них какая много это`),
+	"zh": wordSet(`的 了 是 在 和 有 我 他 她 它 这 那 也 就 不 人 都 一 个 上 们 到 说 要 会 着 没有 看 好
+		自己 这个 那个 我们 他们 你们 以及 与 及 或 但 而 被 把 从 对 为 于 之 其 中 等`),
+	"ja": wordSet(`の に は を た が で て と し れ さ ある いる も する から な こと として い や れる など
+		なっ ない この ため その あっ よう また もの という あり まで られ なる へ か だ これ です ます
+		でき それ ので なお および さらに でも`),
+}
+
+func wordSet(words string) map[string]bool {
+	fold := newFolder()
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[fold(w)] = true
+	}
+	return set
+}
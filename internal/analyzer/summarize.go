@@ -0,0 +1,144 @@
+package analyzer
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// SummaryOptions sets the target length of an extractive summary
+// Both limits apply when set; with neither, DefaultSummarySentences is used
+type SummaryOptions struct {
+	Sentences int    // Maximum number of sentences
+	MaxChars  int    // Maximum length in characters, separating spaces included
+	Language  string // ISO 639-1 code for tokenization; empty detects it from the text
+}
+
+// DefaultSummarySentences matches the 1-2 sentence summaries asked of the LLM
+const DefaultSummarySentences = 2
+
+// DefaultSummaryOptions returns two-sentence summaries
+func DefaultSummaryOptions() SummaryOptions {
+	return SummaryOptions{Sentences: DefaultSummarySentences}
+}
+
+// Summarize picks the most central sentences of text with TextRank and returns
+// them in their original order
+// Algorithm: split sentences (abbreviation-aware) -> link sentence pairs weighted by
+// shared content words, normalized by sentence length -> rank with PageRank ->
+// take sentences by rank while they fit the target length -> restore text order
+// When the best sentence alone exceeds MaxChars it is cut at a word boundary
+func Summarize(text string, opts SummaryOptions) []Sentence {
+	sentences := SplitSentences(text)
+	if len(sentences) == 0 {
+		return nil
+	}
+	if opts.Sentences <= 0 && opts.MaxChars <= 0 {
+		opts.Sentences = DefaultSummarySentences
+	}
+	lang := opts.Language
+	if lang == "" {
+		lang, _ = DetectLanguage(text)
+	}
+
+	// STEP 1: Content words of each sentence, grouped by lemma
+	words := make([]map[string]bool, len(sentences))
+	for i, s := range sentences {
+		words[i] = make(map[string]bool)
+		for _, t := range Tokenize(s.Text, lang) {
+			if !IsStopword(t, lang) && !isNumeric(t) {
+				words[i][Lemmatize(t, lang)] = true
+			}
+		}
+	}
+
+	// STEP 2: Similarity graph (Mihalcea & Tarau, 2004); log(1+n) keeps one-word sentences defined
+	edges := make([]map[int]float64, len(sentences))
+	for i := range edges {
+		edges[i] = make(map[int]float64)
+	}
+	for i := range sentences {
+		for j := i + 1; j < len(sentences); j++ {
+			shared := 0
+			for w := range words[i] {
+				if words[j][w] {
+					shared++
+				}
+			}
+			if shared == 0 {
+				continue
+			}
+			sim := float64(shared) / (math.Log(1+float64(len(words[i]))) + math.Log(1+float64(len(words[j]))))
+			edges[i][j], edges[j][i] = sim, sim
+		}
+	}
+
+	// STEP 3: Rank; ties keep text order, so the lead sentence wins in an unconnected text
+	rank := pageRank(edges)
+	order := make([]int, len(sentences))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return rank[order[a]] > rank[order[b]] })
+
+	// STEP 4: Select by rank within the length budget; sentences sharing no words
+	// with the rest are off-topic filler unless the whole text is unconnected
+	connected := false
+	for _, e := range edges {
+		connected = connected || len(e) > 0
+	}
+	var picked []Sentence
+	chars := 0
+	for _, i := range order {
+		if opts.Sentences > 0 && len(picked) == opts.Sentences {
+			break
+		}
+		if connected && len(edges[i]) == 0 {
+			continue
+		}
+		n := utf8.RuneCountInString(sentences[i].Text)
+		if len(picked) > 0 {
+			n++ // Joining space
+		}
+		if opts.MaxChars > 0 && chars+n > opts.MaxChars {
+			if len(picked) == 0 {
+				return []Sentence{truncateSentence(sentences[i], opts.MaxChars)}
+			}
+			continue // A shorter, lower-ranked sentence may still fit
+		}
+		picked = append(picked, sentences[i])
+		chars += n
+	}
+
+	// STEP 5: Original order reads naturally
+	sort.Slice(picked, func(a, b int) bool { return picked[a].Start < picked[b].Start })
+	return picked
+}
+
+// SummarizeText is Summarize joined into a single string
+func SummarizeText(text string, opts SummaryOptions) string {
+	sentences := Summarize(text, opts)
+	parts := make([]string, len(sentences))
+	for i, s := range sentences {
+		parts[i] = s.Text
+	}
+	return strings.Join(parts, " ")
+}
+
+// truncateSentence cuts s to at most maxChars characters at a word boundary, ending with an ellipsis
+func truncateSentence(s Sentence, maxChars int) Sentence {
+	runes := []rune(s.Text)
+	if maxChars <= 1 || len(runes) <= maxChars {
+		return s
+	}
+	cut := maxChars - 1 // Room for "…"
+	for i := cut; i > 0; i-- {
+		if runes[i] == ' ' {
+			cut = i
+			break
+		}
+	}
+	text := strings.TrimRight(string(runes[:cut]), " ,;:")
+	return Sentence{Text: text + "…", Start: s.Start, End: s.Start + utf8.RuneCountInString(text)}
+}
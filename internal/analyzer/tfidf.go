@@ -0,0 +1,113 @@
+package analyzer
+
+import (
+	"math"
+	"sort"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+)
+
+// Weighting selects how corpus statistics turn term counts into keyword scores
+type Weighting string
+
+const (
+	WeightTFIDF Weighting = "tfidf" // Log-scaled term frequency x smoothed inverse document frequency
+	WeightBM25  Weighting = "bm25"  // Okapi BM25 term weight, saturating and length-normalized
+)
+
+// Okapi BM25 defaults, as in search.RankBM25
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// ParseWeighting validates a weighting name; empty selects TF-IDF
+func ParseWeighting(name string) (Weighting, bool) {
+	switch Weighting(name) {
+	case "", WeightTFIDF:
+		return WeightTFIDF, true
+	case WeightBM25:
+		return WeightBM25, true
+	}
+	return "", false
+}
+
+// CorpusStats are document-frequency statistics for the stored documents of one language
+type CorpusStats struct {
+	Documents int            // Documents counted
+	Tokens    int            // Content tokens across those documents, for BM25 length normalization
+	DF        map[string]int // Term key -> documents containing it
+}
+
+// Include returns the statistics with doc counted, for scoring a document
+// before it has been stored; the receiver is not modified
+func (c CorpusStats) Include(doc DocumentTerms) CorpusStats {
+	out := CorpusStats{
+		Documents: c.Documents + 1,
+		Tokens:    c.Tokens + doc.Length,
+		DF:        make(map[string]int, len(doc.Terms)),
+	}
+	for key := range doc.Terms {
+		out.DF[key] = c.DF[key] + 1
+	}
+	return out
+}
+
+// ScoreKeywords ranks the terms of doc by how distinctive they are in the corpus
+// Algorithm: weight each term by its count and document frequency -> sort by score ->
+// select top N (topN <= 0 keeps all)
+// stats must already count doc (see CorpusStats.Include); ties are broken by first occurrence
+func ScoreKeywords(doc DocumentTerms, stats CorpusStats, weighting Weighting, topN int) []models.KeywordScore {
+	keys := doc.Keys()
+	scores := make(map[string]float64, len(keys))
+	for _, key := range keys {
+		scores[key] = termWeight(doc.Terms[key].Count, doc.Length, stats.DF[key], stats, weighting)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return scores[keys[i]] > scores[keys[j]]
+	})
+	if topN > 0 && len(keys) > topN {
+		keys = keys[:topN]
+	}
+
+	out := make([]models.KeywordScore, 0, len(keys))
+	for _, key := range keys {
+		t := doc.Terms[key]
+		out = append(out, models.KeywordScore{
+			Keyword:   t.Display,
+			Score:     math.Round(scores[key]*10000) / 10000,
+			Count:     t.Count,
+			Documents: stats.DF[key],
+		})
+	}
+	return out
+}
+
+// termWeight scores one term occurring count times in a document of length content tokens
+func termWeight(count, length, df int, stats CorpusStats, weighting Weighting) float64 {
+	n := float64(stats.Documents)
+	if df < 1 {
+		df = 1 // The document itself contains the term
+	}
+	if n < float64(df) {
+		n = float64(df)
+	}
+
+	if weighting == WeightBM25 {
+		idf := math.Log(1 + (n-float64(df)+0.5)/(float64(df)+0.5))
+		avg := float64(length)
+		if stats.Documents > 0 && stats.Tokens > 0 {
+			avg = float64(stats.Tokens) / float64(stats.Documents)
+		}
+		norm := 1.0
+		if avg > 0 {
+			norm = 1 - bm25B + bm25B*float64(length)/avg
+		}
+		tf := float64(count)
+		return idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+	}
+
+	// Smoothed IDF stays positive, so a term in every document still ranks by frequency
+	idf := math.Log((1+n)/(1+float64(df))) + 1
+	return (1 + math.Log(float64(count))) * idf
+}
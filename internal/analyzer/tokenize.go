@@ -0,0 +1,144 @@
+package analyzer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Tokenize splits text into case-folded word tokens appropriate for lang
+// Words follow the Unicode word boundary rules (UAX #29) and are NFC-normalized,
+// so precomposed and decomposed accents give the same token. Latin languages drop
+// elided articles ("l'", "d'") and English possessives; Chinese and Japanese, which
+// don't separate words with spaces, are split into overlapping character bigrams
+func Tokenize(text, lang string) []string {
+	spans := TokenizeSpans(text, lang)
+	tokens := make([]string, len(spans))
+	for i, t := range spans {
+		tokens[i] = t.Text
+	}
+	return tokens
+}
+
+// Token is a normalized token with the rune offsets (end exclusive) of the
+// source text it was derived from
+type Token struct {
+	Text  string
+	Start int
+	End   int
+}
+
+// TokenizeSpans is Tokenize keeping each token's position in text
+func TokenizeSpans(text, lang string) []Token {
+	toRune := runeOffsets(text)
+	fold := newFolder()
+	var tokens []Token
+	spans := wordSpans(text)
+	for i := 0; i < len(spans); i++ {
+		m := spans[i]
+		// Ideographs and kana are separate words; rejoin adjacent ones into a run for bigrams
+		if isCJK(text[m[0]:m[1]]) {
+			for i+1 < len(spans) && spans[i+1][0] == m[1] && isCJK(text[spans[i+1][0]:spans[i+1][1]]) {
+				i++
+				m[1] = spans[i][1]
+			}
+			run := fold(text[m[0]:m[1]])
+			start := toRune[m[0]]
+			for j, bigram := range cjkBigrams(run) {
+				n := utf8.RuneCountInString(bigram)
+				tokens = append(tokens, Token{Text: bigram, Start: start + j, End: start + j + n})
+			}
+			continue
+		}
+
+		word := text[m[0]:m[1]]
+		start, end := toRune[m[0]], toRune[m[1]]
+		tok := fold(word)
+		switch lang {
+		case "fr", "it":
+			if j := strings.IndexAny(word, "'’"); j > 0 && utf8.RuneCountInString(word[:j]) <= 4 {
+				start += utf8.RuneCountInString(word[:j]) + 1
+				tok = tok[strings.IndexRune(tok, '\'')+1:] // l'homme -> homme, dell'anno -> anno
+			}
+		case "en":
+			if strings.HasSuffix(tok, "'s") {
+				tok = strings.TrimSuffix(tok, "'s")
+				end -= 2
+			}
+		}
+		tokens = append(tokens, Token{Text: tok, Start: start, End: end})
+	}
+	return tokens
+}
+
+// Fold normalizes a word or phrase the way Tokenize normalizes tokens
+func Fold(s string) string {
+	return newFolder()(s)
+}
+
+// newFolder returns a function normalizing words for comparison: NFC, Unicode
+// case folding ("Straße" and "STRASSE" both become "strasse") and typographic
+// apostrophes as ASCII ones
+// A Caser keeps state between calls, so each tokenizer run makes its own
+func newFolder() func(string) string {
+	caser := cases.Fold()
+	return func(word string) string {
+		return strings.ReplaceAll(caser.String(norm.NFC.String(word)), "’", "'")
+	}
+}
+
+// IsStopword reports whether token is a stopword in lang
+// Unknown languages fall back to the English list; CJK bigrams count as
+// stopwords when either character is one (e.g. "我的")
+func IsStopword(token, lang string) bool {
+	list, ok := Stopwords[lang]
+	if !ok {
+		list = Stopwords["en"]
+	}
+	if list[token] {
+		return true
+	}
+	if isCJK(token) {
+		for _, r := range token {
+			if list[string(r)] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// wordTokens returns the words of text as they appear in it
+func wordTokens(text string) []string {
+	spans := wordSpans(text)
+	words := make([]string, len(spans))
+	for i, m := range spans {
+		words[i] = text[m[0]:m[1]]
+	}
+	return words
+}
+
+func isCJK(tok string) bool {
+	for _, r := range tok {
+		if !unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) && r != '\u30FC' && r != '\uFF70' {
+			return false // U+30FC and U+FF70 are the prolonged sound marks of katakana words ("コーヒー")
+		}
+	}
+	return tok != ""
+}
+
+// cjkBigrams splits an unsegmented CJK run into overlapping character pairs
+func cjkBigrams(run string) []string {
+	runes := []rune(run)
+	if len(runes) < 2 {
+		return []string{run}
+	}
+	out := make([]string, 0, len(runes)-1)
+	for i := 0; i+1 < len(runes); i++ {
+		out = append(out, string(runes[i:i+2]))
+	}
+	return out
+}
@@ -0,0 +1,264 @@
+package cluster
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
+)
+
+// Defaults for Options fields left at zero
+const (
+	DefaultIterations = 50
+	DefaultLabelTerms = 5
+)
+
+// Document is a stored analysis to cluster, reduced to its content terms
+type Document struct {
+	ID    string
+	Terms analyzer.DocumentTerms
+}
+
+// Options tune KMeans
+type Options struct {
+	K          int   // Number of clusters; 0 picks DefaultK
+	Iterations int   // Upper bound on assignment rounds
+	LabelTerms int   // Top centroid terms reported per cluster
+	Seed       int64 // Seeds k-means++ so the same corpus gives the same clusters
+}
+
+// Cluster is a group of documents about the same theme
+type Cluster struct {
+	Terms   []string // Highest-weighted centroid terms (display forms), most central first
+	Members []Member // Most central first
+}
+
+// Member is a document in a cluster
+type Member struct {
+	ID         string
+	Similarity float64 // Cosine similarity to the cluster centroid (0-1)
+}
+
+// vector is a sparse, L2-normalized term-weight vector
+type vector map[string]float64
+
+// DefaultK is the rule-of-thumb cluster count sqrt(n/2), at least 1
+func DefaultK(n int) int {
+	k := int(math.Round(math.Sqrt(float64(n) / 2)))
+	if k < 1 {
+		k = 1
+	}
+	return k
+}
+
+// KMeans groups documents by the terms they share
+// Algorithm: TF-IDF vectors over the given documents -> k-means++ seeding -> spherical
+// k-means (cosine similarity, normalized mean centroids) until assignments settle ->
+// drop empty clusters -> order clusters by size and members by centrality
+// Documents without content terms are left out
+func KMeans(docs []Document, opts Options) []Cluster {
+	vectors, ids, display := tfidfVectors(docs)
+	if len(vectors) == 0 {
+		return nil
+	}
+	k := opts.K
+	if k <= 0 {
+		k = DefaultK(len(vectors))
+	}
+	if k > len(vectors) {
+		k = len(vectors)
+	}
+	iterations := opts.Iterations
+	if iterations <= 0 {
+		iterations = DefaultIterations
+	}
+	labelTerms := opts.LabelTerms
+	if labelTerms <= 0 {
+		labelTerms = DefaultLabelTerms
+	}
+
+	centroids := seedCentroids(vectors, k, rand.New(rand.NewSource(opts.Seed)))
+	assign := make([]int, len(vectors))
+	for i := range assign {
+		assign[i] = -1
+	}
+	for round := 0; round < iterations; round++ {
+		changed := false
+		for i, v := range vectors {
+			best, _ := nearest(v, centroids)
+			if best != assign[i] {
+				assign[i], changed = best, true
+			}
+		}
+		if !changed {
+			break
+		}
+		centroids = recenter(vectors, assign, k)
+	}
+
+	var clusters []Cluster
+	for c, centroid := range centroids {
+		var members []Member
+		for i, v := range vectors {
+			if assign[i] == c {
+				members = append(members, Member{ID: ids[i], Similarity: round3(dot(v, centroid))})
+			}
+		}
+		if len(members) == 0 {
+			continue
+		}
+		sort.SliceStable(members, func(a, b int) bool { return members[a].Similarity > members[b].Similarity })
+		clusters = append(clusters, Cluster{Terms: topTerms(centroid, display, labelTerms), Members: members})
+	}
+	sort.SliceStable(clusters, func(a, b int) bool { return len(clusters[a].Members) > len(clusters[b].Members) })
+	return clusters
+}
+
+// tfidfVectors weights each document's terms by log-scaled frequency and smoothed IDF
+// (as analyzer.ScoreKeywords does), keeping the first display form seen for each term
+func tfidfVectors(docs []Document) ([]vector, []string, map[string]string) {
+	df := make(map[string]int)
+	display := make(map[string]string)
+	for _, d := range docs {
+		for key, t := range d.Terms.Terms {
+			df[key]++
+			if _, ok := display[key]; !ok {
+				display[key] = t.Display
+			}
+		}
+	}
+
+	n := float64(len(docs))
+	var vectors []vector
+	var ids []string
+	for _, d := range docs {
+		if len(d.Terms.Terms) == 0 {
+			continue
+		}
+		v := make(vector, len(d.Terms.Terms))
+		for key, t := range d.Terms.Terms {
+			idf := math.Log((1+n)/(1+float64(df[key]))) + 1
+			v[key] = (1 + math.Log(float64(t.Count))) * idf
+		}
+		vectors = append(vectors, normalize(v))
+		ids = append(ids, d.ID)
+	}
+	return vectors, ids, display
+}
+
+// seedCentroids picks k starting centroids with k-means++ (Arthur & Vassilvitskii, 2007):
+// each next seed is drawn with probability proportional to its squared cosine distance
+// from the nearest seed so far
+func seedCentroids(vectors []vector, k int, rng *rand.Rand) []vector {
+	centroids := []vector{vectors[rng.Intn(len(vectors))]}
+	for len(centroids) < k {
+		weights := make([]float64, len(vectors))
+		total := 0.0
+		for i, v := range vectors {
+			_, sim := nearest(v, centroids)
+			d := 1 - sim
+			weights[i] = d * d
+			total += weights[i]
+		}
+		if total == 0 {
+			break // Every document coincides with a seed
+		}
+		r := rng.Float64() * total
+		pick := len(vectors) - 1
+		for i, w := range weights {
+			if r < w {
+				pick = i
+				break
+			}
+			r -= w
+		}
+		centroids = append(centroids, vectors[pick])
+	}
+	return centroids
+}
+
+// epsilon absorbs the rounding differences of summing map entries in random order,
+// so that ties stay ties from run to run
+const epsilon = 1e-9
+
+// nearest returns the index of the most similar centroid (lowest index on ties) and the similarity
+func nearest(v vector, centroids []vector) (int, float64) {
+	best, bestSim := 0, math.Inf(-1)
+	for c, centroid := range centroids {
+		if sim := dot(v, centroid); sim > bestSim+epsilon {
+			best, bestSim = c, sim
+		}
+	}
+	return best, bestSim
+}
+
+// recenter averages each cluster's members into a normalized centroid
+func recenter(vectors []vector, assign []int, k int) []vector {
+	centroids := make([]vector, k)
+	for c := range centroids {
+		centroids[c] = make(vector)
+	}
+	for i, v := range vectors {
+		for key, w := range v {
+			centroids[assign[i]][key] += w
+		}
+	}
+	for c := range centroids {
+		centroids[c] = normalize(centroids[c])
+	}
+	return centroids
+}
+
+// topTerms lists the n highest-weighted terms of a centroid; ties are broken alphabetically
+func topTerms(centroid vector, display map[string]string, n int) []string {
+	keys := make([]string, 0, len(centroid))
+	for key := range centroid {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if math.Abs(centroid[keys[i]]-centroid[keys[j]]) > epsilon {
+			return centroid[keys[i]] > centroid[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	out := make([]string, len(keys))
+	for i, key := range keys {
+		out[i] = display[key]
+	}
+	return out
+}
+
+// dot iterates the smaller vector; for normalized vectors it is the cosine similarity
+func dot(a, b vector) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	sum := 0.0
+	for key, w := range a {
+		sum += w * b[key]
+	}
+	return sum
+}
+
+func normalize(v vector) vector {
+	norm := 0.0
+	for _, w := range v {
+		norm += w * w
+	}
+	if norm == 0 {
+		return v
+	}
+	norm = math.Sqrt(norm)
+	for key := range v {
+		v[key] /= norm
+	}
+	return v
+}
+
+func round3(x float64) float64 {
+	return math.Round(x*1000) / 1000
+}
@@ -0,0 +1,121 @@
+package cluster
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
+)
+
+// doc builds a document whose terms are the space-separated words of text
+func doc(id, text string) Document {
+	terms := make(map[string]*analyzer.Term)
+	for i, w := range strings.Fields(text) {
+		if t, ok := terms[w]; ok {
+			t.Count++
+			continue
+		}
+		terms[w] = &analyzer.Term{Count: 1, First: i, Display: w}
+	}
+	return Document{ID: id, Terms: analyzer.DocumentTerms{Language: "en", Terms: terms}}
+}
+
+var themed = []Document{
+	doc("solar1", "solar panel energy battery grid"),
+	doc("solar2", "solar energy panel rooftop"),
+	doc("solar3", "battery solar grid energy storage"),
+	doc("bank1", "bank interest rate inflation"),
+	doc("bank2", "inflation bank rate mortgage"),
+	doc("bank3", "interest mortgage bank lender rate"),
+	doc("match1", "football match goal league"),
+	doc("match2", "league goal striker football"),
+}
+
+func TestKMeansSeedDeterminism(t *testing.T) {
+	for _, seed := range []int64{0, 1, 42} {
+		first := KMeans(themed, Options{K: 3, Seed: seed})
+		for run := 0; run < 5; run++ {
+			if again := KMeans(themed, Options{K: 3, Seed: seed}); !reflect.DeepEqual(again, first) {
+				t.Fatalf("seed %d: run %d gave %+v, first run %+v", seed, run, again, first)
+			}
+		}
+	}
+}
+
+func TestKMeansGroupsThemes(t *testing.T) {
+	clusters := KMeans(themed, Options{K: 3, Seed: 1})
+	var groups []string
+	for _, c := range clusters {
+		var ids []string
+		for _, m := range c.Members {
+			ids = append(ids, m.ID)
+		}
+		sort.Strings(ids)
+		groups = append(groups, strings.Join(ids, ","))
+	}
+	sort.Strings(groups)
+	want := []string{"bank1,bank2,bank3", "match1,match2", "solar1,solar2,solar3"}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("clusters %q, want %q", groups, want)
+	}
+	if len(clusters) > 0 && len(clusters[0].Terms) != DefaultLabelTerms {
+		t.Errorf("cluster labelled with %d terms, want %d", len(clusters[0].Terms), DefaultLabelTerms)
+	}
+}
+
+func TestKMeansClampsK(t *testing.T) {
+	docs := []Document{doc("a", "solar energy"), doc("b", "bank rate")}
+	clusters := KMeans(docs, Options{K: 10, Seed: 1})
+	if len(clusters) != 2 {
+		t.Fatalf("K=10 over 2 documents gave %d clusters, want 2", len(clusters))
+	}
+	for _, c := range clusters {
+		if len(c.Members) != 1 || c.Members[0].Similarity != 1 {
+			t.Errorf("cluster %+v, want one member at similarity 1", c.Members)
+		}
+	}
+}
+
+func TestKMeansDocumentsWithoutTerms(t *testing.T) {
+	if clusters := KMeans([]Document{doc("a", ""), doc("b", "")}, Options{}); clusters != nil {
+		t.Errorf("documents without terms gave %+v, want no clusters", clusters)
+	}
+	if clusters := KMeans(nil, Options{}); clusters != nil {
+		t.Errorf("no documents gave %+v, want no clusters", clusters)
+	}
+
+	docs := append([]Document{doc("empty", "")}, themed...)
+	for _, c := range KMeans(docs, Options{K: 3, Seed: 1}) {
+		for _, m := range c.Members {
+			if m.ID == "empty" {
+				t.Error("a document without terms was clustered")
+			}
+		}
+	}
+}
+
+func TestKMeansDropsEmptyClusters(t *testing.T) {
+	// Identical documents leave k-means++ a single seed; the other centroids stay empty
+	docs := []Document{doc("a", "solar energy"), doc("b", "solar energy"), doc("c", "solar energy")}
+	clusters := KMeans(docs, Options{K: 3, Seed: 1})
+	if len(clusters) != 1 {
+		t.Fatalf("got %d clusters, want the empty ones dropped", len(clusters))
+	}
+	if len(clusters[0].Members) != 3 {
+		t.Errorf("cluster has %d members, want 3", len(clusters[0].Members))
+	}
+	if !reflect.DeepEqual(clusters[0].Terms, []string{"energy", "solar"}) {
+		t.Errorf("cluster terms %q, want [energy solar]", clusters[0].Terms)
+	}
+}
+
+func TestDefaultK(t *testing.T) {
+	tests := []struct{ n, want int }{{0, 1}, {1, 1}, {2, 1}, {8, 2}, {50, 5}, {200, 10}}
+	for _, tt := range tests {
+		if got := DefaultK(tt.n); got != tt.want {
+			t.Errorf("DefaultK(%d) = %d, want %d", tt.n, got, tt.want)
+		}
+	}
+}
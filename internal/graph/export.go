@@ -0,0 +1,231 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+)
+
+// Export formats and their content types
+var ContentTypes = map[string]string{
+	"json":    "application/json",
+	"graphml": "application/graphml+xml",
+	"gexf":    "application/gexf+xml",
+	"dot":     "text/vnd.graphviz",
+}
+
+// Write encodes g in one of the XML or DOT formats of ContentTypes
+func Write(w io.Writer, g models.Graph, format string) error {
+	switch format {
+	case "graphml":
+		return WriteGraphML(w, g)
+	case "gexf":
+		return WriteGEXF(w, g)
+	case "dot":
+		return WriteDOT(w, g)
+	}
+	return fmt.Errorf("unknown graph format %q", format)
+}
+
+// nodeIDs maps concept keys to the short ids used by the exports (n0, n1, ...)
+// Keys contain spaces and punctuation some tools reject as identifiers
+func nodeIDs(g models.Graph) map[string]string {
+	ids := make(map[string]string, len(g.Nodes))
+	for i, n := range g.Nodes {
+		ids[n.ID] = "n" + strconv.Itoa(i)
+	}
+	return ids
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLDoc struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+// WriteGraphML encodes g as GraphML with label, kinds and documents on nodes and
+// weight (shared documents) and pmi on edges
+func WriteGraphML(w io.Writer, g models.Graph) error {
+	doc := graphMLDoc{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", Name: "label", Type: "string"},
+			{ID: "kinds", For: "node", Name: "kinds", Type: "string"},
+			{ID: "documents", For: "node", Name: "documents", Type: "int"},
+			{ID: "weight", For: "edge", Name: "weight", Type: "int"},
+			{ID: "pmi", For: "edge", Name: "pmi", Type: "double"},
+		},
+	}
+	doc.Graph.ID = "cooccurrence"
+	doc.Graph.EdgeDefault = "undirected"
+	ids := nodeIDs(g)
+	for _, n := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: ids[n.ID], Data: []graphMLData{
+			{Key: "label", Value: n.Label},
+			{Key: "kinds", Value: strings.Join(n.Kinds, ",")},
+			{Key: "documents", Value: strconv.Itoa(n.Documents)},
+		}})
+	}
+	for i, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			ID:     "e" + strconv.Itoa(i),
+			Source: ids[e.Source],
+			Target: ids[e.Target],
+			Data: []graphMLData{
+				{Key: "weight", Value: strconv.Itoa(e.Documents)},
+				{Key: "pmi", Value: formatFloat(e.PMI)},
+			},
+		})
+	}
+	return writeXML(w, doc)
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type gexfNode struct {
+	ID     string      `xml:"id,attr"`
+	Label  string      `xml:"label,attr"`
+	Values []gexfValue `xml:"attvalues>attvalue"`
+}
+
+type gexfEdge struct {
+	ID     string      `xml:"id,attr"`
+	Source string      `xml:"source,attr"`
+	Target string      `xml:"target,attr"`
+	Weight int         `xml:"weight,attr"`
+	Values []gexfValue `xml:"attvalues>attvalue"`
+}
+
+type gexfDoc struct {
+	XMLName xml.Name `xml:"gexf"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	Version string   `xml:"version,attr"`
+	Graph   struct {
+		DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+		Mode            string           `xml:"mode,attr"`
+		Attributes      []gexfAttributes `xml:"attributes"`
+		Nodes           []gexfNode       `xml:"nodes>node"`
+		Edges           []gexfEdge       `xml:"edges>edge"`
+	} `xml:"graph"`
+}
+
+// WriteGEXF encodes g as GEXF 1.3; shared documents are the edge weight
+func WriteGEXF(w io.Writer, g models.Graph) error {
+	doc := gexfDoc{XMLNS: "http://gexf.net/1.3", Version: "1.3"}
+	doc.Graph.DefaultEdgeType = "undirected"
+	doc.Graph.Mode = "static"
+	doc.Graph.Attributes = []gexfAttributes{
+		{Class: "node", Attributes: []gexfAttribute{
+			{ID: "kinds", Title: "kinds", Type: "string"},
+			{ID: "documents", Title: "documents", Type: "integer"},
+		}},
+		{Class: "edge", Attributes: []gexfAttribute{
+			{ID: "pmi", Title: "pmi", Type: "double"},
+		}},
+	}
+	ids := nodeIDs(g)
+	for _, n := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, gexfNode{ID: ids[n.ID], Label: n.Label, Values: []gexfValue{
+			{For: "kinds", Value: strings.Join(n.Kinds, ",")},
+			{For: "documents", Value: strconv.Itoa(n.Documents)},
+		}})
+	}
+	for i, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, gexfEdge{
+			ID:     "e" + strconv.Itoa(i),
+			Source: ids[e.Source],
+			Target: ids[e.Target],
+			Weight: e.Documents,
+			Values: []gexfValue{{For: "pmi", Value: formatFloat(e.PMI)}},
+		})
+	}
+	return writeXML(w, doc)
+}
+
+// WriteDOT encodes g as an undirected Graphviz graph; edge penwidth follows shared documents
+func WriteDOT(w io.Writer, g models.Graph) error {
+	bw := bufio.NewWriter(w)
+	ids := nodeIDs(g)
+	fmt.Fprintln(bw, "graph cooccurrence {")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(bw, "  %s [label=%s, kinds=%s, documents=%d];\n",
+			ids[n.ID], dotQuote(n.Label), dotQuote(strings.Join(n.Kinds, ",")), n.Documents)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(bw, "  %s -- %s [weight=%d, penwidth=%d, pmi=%s];\n",
+			ids[e.Source], ids[e.Target], e.Documents, e.Documents, formatFloat(e.PMI))
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+func writeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
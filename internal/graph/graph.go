@@ -0,0 +1,220 @@
+package graph
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+)
+
+// Concept kinds besides entity types
+const (
+	KindTopic   = "topic"
+	KindKeyword = "keyword"
+)
+
+// Concept is a topic, keyword or entity of one document
+type Concept struct {
+	Label string
+	Kind  string
+}
+
+// Builder accumulates concept co-occurrences document by document
+type Builder struct {
+	documents int
+	nodes     map[string]*nodeStats
+	pairs     map[[2]string]int
+}
+
+type nodeStats struct {
+	label     string
+	kinds     map[string]bool
+	documents int
+}
+
+// NewBuilder returns an empty builder
+func NewBuilder() *Builder {
+	return &Builder{nodes: make(map[string]*nodeStats), pairs: make(map[[2]string]int)}
+}
+
+// Key case-folds and collapses whitespace so "Machine  Learning" and "machine learning" are one node
+func Key(label string) string {
+	return strings.Join(strings.Fields(analyzer.Fold(label)), " ")
+}
+
+// Add records one document; a concept listed several times counts once
+func (b *Builder) Add(concepts []Concept) {
+	b.documents++
+	var keys []string
+	seen := make(map[string]bool, len(concepts))
+	for _, c := range concepts {
+		key := Key(c.Label)
+		if key == "" {
+			continue
+		}
+		n, ok := b.nodes[key]
+		if !ok {
+			n = &nodeStats{label: strings.Join(strings.Fields(c.Label), " "), kinds: make(map[string]bool)}
+			b.nodes[key] = n
+		}
+		n.kinds[c.Kind] = true
+		if !seen[key] {
+			seen[key] = true
+			n.documents++
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for i := range keys {
+		for j := i + 1; j < len(keys); j++ {
+			b.pairs[[2]string{keys[i], keys[j]}]++
+		}
+	}
+}
+
+// Build returns every concept and the edges shared by at least minDocuments documents,
+// nodes by document count and edges by shared documents, then PMI
+func (b *Builder) Build(minDocuments int) models.Graph {
+	g := models.Graph{Documents: b.documents, Nodes: []models.GraphNode{}, Edges: []models.GraphEdge{}}
+	for key, n := range b.nodes {
+		kinds := make([]string, 0, len(n.kinds))
+		for k := range n.kinds {
+			kinds = append(kinds, k)
+		}
+		sort.Strings(kinds)
+		g.Nodes = append(g.Nodes, models.GraphNode{ID: key, Label: n.label, Kinds: kinds, Documents: n.documents})
+	}
+	total := float64(b.documents)
+	for pair, count := range b.pairs {
+		if count < minDocuments {
+			continue
+		}
+		a, c := b.nodes[pair[0]], b.nodes[pair[1]]
+		pmi := math.Log(float64(count) * total / (float64(a.documents) * float64(c.documents)))
+		g.Edges = append(g.Edges, models.GraphEdge{
+			Source:    pair[0],
+			Target:    pair[1],
+			Documents: count,
+			PMI:       math.Round(pmi*1000) / 1000,
+		})
+	}
+	sortGraph(&g)
+	return g
+}
+
+// Neighborhood returns the concepts within depth edges of center and the edges between them
+// Nearer concepts come first; within a ring, stronger links (shared documents, then PMI) win
+// the remaining places up to maxNodes. ok is false when center is not in the graph
+func Neighborhood(g models.Graph, center string, depth, maxNodes int) (models.Graph, bool) {
+	key := Key(center)
+	byID := make(map[string]models.GraphNode, len(g.Nodes))
+	for _, n := range g.Nodes {
+		byID[n.ID] = n
+	}
+	if _, ok := byID[key]; !ok {
+		return models.Graph{}, false
+	}
+	adjacent := make(map[string][]models.GraphEdge)
+	for _, e := range g.Edges {
+		adjacent[e.Source] = append(adjacent[e.Source], e)
+		adjacent[e.Target] = append(adjacent[e.Target], e)
+	}
+
+	included := map[string]bool{key: true}
+	order := []string{key}
+	ring := []string{key}
+	for d := 0; d < depth && len(ring) > 0 && len(order) < maxNodes; d++ {
+		// Best link from the previous ring to each new concept
+		best := make(map[string]models.GraphEdge)
+		for _, id := range ring {
+			for _, e := range adjacent[id] {
+				other := e.Target
+				if other == id {
+					other = e.Source
+				}
+				if included[other] {
+					continue
+				}
+				if b, ok := best[other]; !ok || stronger(e, b) {
+					best[other] = e
+				}
+			}
+		}
+		next := make([]string, 0, len(best))
+		for id := range best {
+			next = append(next, id)
+		}
+		sort.Slice(next, func(i, j int) bool {
+			a, b := best[next[i]], best[next[j]]
+			if stronger(a, b) != stronger(b, a) {
+				return stronger(a, b)
+			}
+			return next[i] < next[j]
+		})
+		if room := maxNodes - len(order); len(next) > room {
+			next = next[:room]
+		}
+		for _, id := range next {
+			included[id] = true
+			order = append(order, id)
+		}
+		ring = next
+	}
+
+	out := models.Graph{Documents: g.Documents, Nodes: make([]models.GraphNode, 0, len(order)), Edges: []models.GraphEdge{}}
+	for _, id := range order {
+		out.Nodes = append(out.Nodes, byID[id])
+	}
+	for _, e := range g.Edges {
+		if included[e.Source] && included[e.Target] {
+			out.Edges = append(out.Edges, e)
+		}
+	}
+	return out, true
+}
+
+// Top keeps the maxNodes concepts in the most documents and the edges between them
+func Top(g models.Graph, maxNodes int) models.Graph {
+	if len(g.Nodes) <= maxNodes {
+		return g
+	}
+	out := models.Graph{Documents: g.Documents, Nodes: g.Nodes[:maxNodes], Edges: []models.GraphEdge{}}
+	included := make(map[string]bool, maxNodes)
+	for _, n := range out.Nodes {
+		included[n.ID] = true
+	}
+	for _, e := range g.Edges {
+		if included[e.Source] && included[e.Target] {
+			out.Edges = append(out.Edges, e)
+		}
+	}
+	return out
+}
+
+func stronger(a, b models.GraphEdge) bool {
+	if a.Documents != b.Documents {
+		return a.Documents > b.Documents
+	}
+	return a.PMI > b.PMI
+}
+
+func sortGraph(g *models.Graph) {
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		if g.Nodes[i].Documents != g.Nodes[j].Documents {
+			return g.Nodes[i].Documents > g.Nodes[j].Documents
+		}
+		return g.Nodes[i].ID < g.Nodes[j].ID
+	})
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if stronger(a, b) != stronger(b, a) {
+			return stronger(a, b)
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.Target < b.Target
+	})
+}
@@ -0,0 +1,11 @@
+package llm
+
+// Embedder turns texts into dense vectors for semantic search
+// It sits alongside LLM so providers can be swapped independently:
+// OpenAIEmbedder for quality, HashingEmbedder for fully offline use
+type Embedder interface {
+	// Embed returns one vector per input text, in input order
+	Embed(texts []string) ([][]float32, error)
+	// Model names the vector space; vectors from different models must not be compared
+	Model() string
+}
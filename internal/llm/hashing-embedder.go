@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
+)
+
+// DefaultHashingDimensions is the vector size used by NewHashingEmbedder when dims <= 0
+const DefaultHashingDimensions = 256
+
+// HashingEmbedder is an offline embedder using the hashing trick
+// Each non-stopword token (and its character trigrams, at half weight, so
+// "electricity" and "electrical" land near each other) is hashed to a signed
+// bucket; counts are log-scaled and the vector is L2-normalised
+// It needs no model or network and is deterministic, which also makes it the test default
+type HashingEmbedder struct {
+	dims int
+}
+
+// Ensure HashingEmbedder implements Embedder
+var _ Embedder = (*HashingEmbedder)(nil)
+
+func NewHashingEmbedder(dims int) *HashingEmbedder {
+	if dims <= 0 {
+		dims = DefaultHashingDimensions
+	}
+	return &HashingEmbedder{dims: dims}
+}
+
+func (h *HashingEmbedder) Model() string {
+	return fmt.Sprintf("hashing-%d", h.dims)
+}
+
+func (h *HashingEmbedder) Embed(texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = h.embed(text)
+	}
+	return vectors, nil
+}
+
+func (h *HashingEmbedder) embed(text string) []float32 {
+	lang, _ := analyzer.DetectLanguage(text)
+
+	// STEP 1: Weighted feature counts (tokens and their character trigrams)
+	counts := make(map[string]float64)
+	for _, tok := range analyzer.Tokenize(text, lang) {
+		if analyzer.IsStopword(tok, lang) {
+			continue
+		}
+		counts["w:"+tok]++
+		runes := []rune("^" + tok + "$")
+		for i := 0; i+3 <= len(runes); i++ {
+			counts["c:"+string(runes[i:i+3])] += 0.5
+		}
+	}
+
+	// STEP 2: Signed feature hashing with sublinear term frequency
+	vec := make([]float32, h.dims)
+	for feature, n := range counts {
+		hasher := fnv.New64a()
+		_, _ = hasher.Write([]byte(feature))
+		sum := hasher.Sum64()
+		sign := float32(1)
+		if sum>>63 == 1 {
+			sign = -1
+		}
+		vec[sum%uint64(h.dims)] += sign * float32(1+math.Log(1+n))
+	}
+
+	// STEP 3: L2 normalisation so dot product equals cosine similarity
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vec {
+			vec[i] *= scale
+		}
+	}
+	return vec
+}
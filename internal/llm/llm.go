@@ -1,5 +1,7 @@
 package llm
 
+import "github.com/gbengafagbola/knowledge-extractor/internal/models"
+
 // LLM interface demonstrates Dependency Inversion Principle
 // High-level modules (server) depend on abstractions (interface), not concretions
 // This enables:
@@ -17,3 +19,92 @@ type LLM interface {
 		err error,
 	)
 }
+
+// EntityExtractor is an optional capability for LLM clients that can extract
+// named entities. Callers type-assert for it and fall back to local rules
+// (analyzer.ExtractEntities) when it is missing or fails.
+// Returned mentions carry surface text only; offsets are computed by the caller.
+type EntityExtractor interface {
+	ExtractEntities(input string) ([]models.Entity, error)
+}
+
+// AnalyzeOptions carries per-request settings for an analysis
+type AnalyzeOptions struct {
+	SourceLanguage string // ISO 639-1 code of the input text, if detected
+	TargetLanguage string // ISO 639-1 code for summary/title/topics; empty means the source language
+}
+
+// Result is the structured output of an analysis, as returned by OptionsAnalyzer
+type Result struct {
+	Summary    string
+	Title      string
+	Topics     []string
+	Sentiment  string
+	Keywords   []string
+	Confidence float64
+	Provider   string // Backend that produced the result ("openai", "mock"); set by Analyze when empty
+}
+
+// OptionsAnalyzer is an optional capability for clients that honour AnalyzeOptions
+// (e.g. writing the summary in a requested output language)
+type OptionsAnalyzer interface {
+	AnalyzeWithOptions(input string, opts AnalyzeOptions) (Result, error)
+}
+
+// Named is an optional capability for clients that report their provider name,
+// recorded with each analysis so fallback output can be told apart
+type Named interface {
+	Name() string
+}
+
+// ProviderName is client's Name, or "llm" when it doesn't report one
+func ProviderName(client LLM) string {
+	if n, ok := client.(Named); ok {
+		return n.Name()
+	}
+	return "llm"
+}
+
+// Analyze runs client with opts when it supports them, otherwise falls back to
+// the plain AnalyzeText call and ignores the options
+// Clients that fall back internally (ResilientClient) set Provider themselves
+func Analyze(client LLM, input string, opts AnalyzeOptions) (Result, error) {
+	if oa, ok := client.(OptionsAnalyzer); ok {
+		res, err := oa.AnalyzeWithOptions(input, opts)
+		if res.Provider == "" {
+			res.Provider = ProviderName(client)
+		}
+		return res, err
+	}
+	summary, title, topics, sentiment, keywords, confidence, err := client.AnalyzeText(input)
+	return Result{
+		Summary:    summary,
+		Title:      title,
+		Topics:     topics,
+		Sentiment:  sentiment,
+		Keywords:   keywords,
+		Confidence: confidence,
+		Provider:   ProviderName(client),
+	}, err
+}
+
+// Source is a retrieved document handed to a QuestionAnswerer
+type Source struct {
+	ID    string
+	Title string
+	Text  string
+}
+
+// QuestionAnswerer is an optional capability for clients that can answer a
+// question from supplied sources only. Returned citations carry the source ID
+// and quote; offsets are verified and filled in by the caller.
+type QuestionAnswerer interface {
+	Answer(question string, sources []Source) (answer string, citations []models.Citation, err error)
+}
+
+// ClusterLabeler is an optional capability for clients that can name a group of
+// documents from its most characteristic terms and a few member titles. Callers
+// fall back to the terms themselves when it is missing or fails.
+type ClusterLabeler interface {
+	LabelCluster(terms []string, titles []string) (string, error)
+}
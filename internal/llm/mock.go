@@ -4,13 +4,21 @@ package llm
 type MockClient struct{}
 
 // Ensure MockClient implements the LLM interface
-var _ LLM = (*MockClient)(nil)
+var (
+	_ LLM   = (*MockClient)(nil)
+	_ Named = (*MockClient)(nil)
+)
 
 // NewMockClient returns a new MockClient
 func NewMockClient() *MockClient {
 	return &MockClient{}
 }
 
+// Name reports the mock as the provider, so analyses with canned output are recognisable
+func (m *MockClient) Name() string {
+	return "mock"
+}
+
 // AnalyzeText implements the LLM interface with static values
 func (m *MockClient) AnalyzeText(text string) (
 	summary string,
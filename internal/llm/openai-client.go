@@ -7,29 +7,215 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
 )
 
 type OpenAIClient struct {
 	apiKey string
 }
 
-// Ensure OpenAIClient implements LLM
-var _ LLM = (*OpenAIClient)(nil)
+// Ensure OpenAIClient implements LLM and the optional capabilities
+var (
+	_ LLM              = (*OpenAIClient)(nil)
+	_ OptionsAnalyzer  = (*OpenAIClient)(nil)
+	_ EntityExtractor  = (*OpenAIClient)(nil)
+	_ QuestionAnswerer = (*OpenAIClient)(nil)
+	_ Named            = (*OpenAIClient)(nil)
+	_ ClusterLabeler   = (*OpenAIClient)(nil)
+)
 
 func NewOpenAIClient() *OpenAIClient {
 	apiKey := os.Getenv("OPENAI_API_KEY")
 	return &OpenAIClient{apiKey: apiKey}
 }
 
+// Name reports OpenAI as the provider
+func (o *OpenAIClient) Name() string {
+	return "openai"
+}
+
 func (o *OpenAIClient) AnalyzeText(input string) (
 	string, string, []string, string, []string, float64, error,
 ) {
+	res, err := o.AnalyzeWithOptions(input, AnalyzeOptions{})
+	if err != nil {
+		return "", "", nil, "", nil, 0, err
+	}
+	return res.Summary, res.Title, res.Topics, res.Sentiment, res.Keywords, res.Confidence, nil
+}
+
+// AnalyzeWithOptions states the source and output language in the prompt so the
+// summary, title and topics come back in the requested language
+func (o *OpenAIClient) AnalyzeWithOptions(input string, opts AnalyzeOptions) (Result, error) {
+	output, err := o.complete(analysisPrompt(input, opts))
+	if err != nil {
+		return Result{}, err
+	}
+
+	var parsed struct {
+		Summary    string   `json:"summary"`
+		Title      string   `json:"title"`
+		Topics     []string `json:"topics"`
+		Sentiment  string   `json:"sentiment"`
+		Keywords   []string `json:"keywords"`
+		Confidence float64  `json:"confidence"`
+	}
+	if err := decodeJSONOutput(output, &parsed); err != nil {
+		// Model ignored the JSON instruction; callers fall back to local analysis
+		return Result{}, err
+	}
+
+	return Result{
+		Summary:    parsed.Summary,
+		Title:      parsed.Title,
+		Topics:     parsed.Topics,
+		Sentiment:  strings.ToLower(strings.TrimSpace(parsed.Sentiment)),
+		Keywords:   parsed.Keywords,
+		Confidence: parsed.Confidence,
+		Provider:   o.Name(),
+	}, nil
+}
+
+// analysisPrompt builds the analysis instruction, naming the input and output languages
+func analysisPrompt(input string, opts AnalyzeOptions) string {
+	var b strings.Builder
+	b.WriteString("Analyze this text and return JSON with fields: summary (1-2 sentences), title, " +
+		"topics (3 key topics), sentiment (positive, neutral or negative), keywords, confidence (0-1).")
+
+	if opts.SourceLanguage != "" {
+		fmt.Fprintf(&b, " The text is written in the language with ISO 639-1 code %q.", opts.SourceLanguage)
+	}
+	target := opts.TargetLanguage
+	if target == "" {
+		target = opts.SourceLanguage
+	}
+	if target != "" {
+		fmt.Fprintf(&b, " Write the summary, title and topics in the language with ISO 639-1 code %q, "+
+			"regardless of the language of the text. Keywords must be copied from the text as written.", target)
+	}
+
+	f := newFence()
+	b.WriteString(" ")
+	b.WriteString(f.rule())
+	b.WriteString("\n\n")
+	b.WriteString(f.wrap("DOCUMENT", input))
+	return b.String()
+}
+
+// ExtractEntities asks the model for named entities as JSON
+// Only types and surface strings are trusted; offsets are located by the caller
+func (o *OpenAIClient) ExtractEntities(input string) ([]models.Entity, error) {
+	f := newFence()
+	output, err := o.complete(fmt.Sprintf(
+		"Extract the named entities (person, organization, location, product, date) from the text below. "+
+			"Return only JSON of the form "+
+			`{"entities":[{"type":"person","name":"canonical name","mentions":["exact text as it appears"]}]}`+
+			". %s\n\n%s",
+		f.rule(), f.wrap("DOCUMENT", input),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed struct {
+		Entities []struct {
+			Type     string   `json:"type"`
+			Name     string   `json:"name"`
+			Mentions []string `json:"mentions"`
+		} `json:"entities"`
+	}
+	if err := decodeJSONOutput(output, &parsed); err != nil {
+		return nil, err
+	}
+
+	entities := make([]models.Entity, 0, len(parsed.Entities))
+	for _, e := range parsed.Entities {
+		entity := models.Entity{Type: e.Type, Name: e.Name}
+		for _, m := range e.Mentions {
+			entity.Mentions = append(entity.Mentions, models.Mention{Text: m})
+		}
+		entities = append(entities, entity)
+	}
+	return entities, nil
+}
+
+// maxSourceChars bounds how much of each source is placed in the answer prompt
+const maxSourceChars = 4000
+
+// Answer asks the model to answer strictly from the numbered sources and to
+// cite them with verbatim quotes
+func (o *OpenAIClient) Answer(question string, sources []Source) (string, []models.Citation, error) {
+	f := newFence()
+	var b strings.Builder
+	b.WriteString("Answer the question using only the sources below. " +
+		"If the sources do not contain the answer, say that you don't know. " +
+		"Return only JSON of the form " +
+		`{"answer":"...","citations":[{"source_id":"...","quote":"sentence copied verbatim from the source"}]}` +
+		". ")
+	b.WriteString(f.rule())
+	b.WriteString("\n\n")
+	b.WriteString(f.wrap("QUESTION", question))
+	b.WriteString("\n\nSources:\n")
+	for _, src := range sources {
+		text := src.Text
+		if len(text) > maxSourceChars {
+			text = strings.ToValidUTF8(text[:maxSourceChars], "")
+		}
+		fmt.Fprintf(&b, "\n[source_id: %s]\n%s\n", src.ID, f.wrap("SOURCE", src.Title+"\n"+text))
+	}
+
+	output, err := o.complete(b.String())
+	if err != nil {
+		return "", nil, err
+	}
+
+	var parsed struct {
+		Answer    string `json:"answer"`
+		Citations []struct {
+			SourceID string `json:"source_id"`
+			Quote    string `json:"quote"`
+		} `json:"citations"`
+	}
+	if err := decodeJSONOutput(output, &parsed); err != nil {
+		return "", nil, err
+	}
+
+	citations := make([]models.Citation, 0, len(parsed.Citations))
+	for _, c := range parsed.Citations {
+		citations = append(citations, models.Citation{AnalysisID: c.SourceID, Quote: c.Quote})
+	}
+	return parsed.Answer, citations, nil
+}
+
+// LabelCluster asks the model for a short theme name covering the terms and titles
+func (o *OpenAIClient) LabelCluster(terms []string, titles []string) (string, error) {
+	f := newFence()
+	output, err := o.complete(fmt.Sprintf(
+		"Name the common theme of a group of documents in at most five words, "+
+			"given their most characteristic terms and some of their titles. "+
+			`Return only JSON of the form {"label":"..."}. %s\n\n%s\n\n%s`,
+		f.rule(), f.wrap("TERMS", strings.Join(terms, ", ")), f.wrap("TITLES", strings.Join(titles, "\n")),
+	))
+	if err != nil {
+		return "", err
+	}
+
+	var parsed struct {
+		Label string `json:"label"`
+	}
+	if err := decodeJSONOutput(output, &parsed); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(parsed.Label), nil
+}
+
+// complete sends a single prompt to the Responses API and returns the first text output
+func (o *OpenAIClient) complete(prompt string) (string, error) {
 	payload := map[string]interface{}{
 		"model": "gpt-5-nano",
-		"input": fmt.Sprintf(
-			"Analyze this text and return JSON with fields: summary, title, topics, sentiment, keywords, confidence.\n\n%s",
-			input,
-		),
+		"input": prompt,
 		"store": false,
 	}
 
@@ -41,15 +227,14 @@ func (o *OpenAIClient) AnalyzeText(input string) (
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return "", "", nil, "", nil, 0, err
+		return "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		var errMsg map[string]interface{}
 		_ = json.NewDecoder(resp.Body).Decode(&errMsg)
-		return "", "", nil, "", nil, 0,
-			fmt.Errorf("error %d: %+v", resp.StatusCode, errMsg)
+		return "", fmt.Errorf("error %d: %+v", resp.StatusCode, errMsg)
 	}
 
 	var parsed struct {
@@ -60,13 +245,26 @@ func (o *OpenAIClient) AnalyzeText(input string) (
 		} `json:"output"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
-		return "", "", nil, "", nil, 0, err
+		return "", err
 	}
 
 	if len(parsed.Output) == 0 || len(parsed.Output[0].Content) == 0 {
-		return "", "", nil, "", nil, 0, fmt.Errorf("empty response")
+		return "", fmt.Errorf("empty response")
 	}
 
-	output := parsed.Output[0].Content[0].Text
-	return output, "Generated Title", []string{"ai"}, "neutral", []string{"go"}, 0.9, nil
+	return parsed.Output[0].Content[0].Text, nil
+}
+
+// decodeJSONOutput parses model output that should be JSON, tolerating
+// markdown code fences and prose around the object
+func decodeJSONOutput(output string, v interface{}) error {
+	start := strings.Index(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return fmt.Errorf("no JSON object in model output")
+	}
+	if err := json.Unmarshal([]byte(output[start:end+1]), v); err != nil {
+		return fmt.Errorf("invalid JSON in model output: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// OpenAIEmbedder calls an OpenAI-compatible /embeddings endpoint
+// OPENAI_BASE_URL points it at other compatible servers (Azure, Ollama, vLLM...)
+type OpenAIEmbedder struct {
+	apiKey  string
+	baseURL string
+	model   string
+}
+
+// Ensure OpenAIEmbedder implements Embedder
+var _ Embedder = (*OpenAIEmbedder)(nil)
+
+func NewOpenAIEmbedder() *OpenAIEmbedder {
+	baseURL := os.Getenv("OPENAI_BASE_URL")
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	model := os.Getenv("EMBEDDING_MODEL")
+	if model == "" {
+		model = "text-embedding-3-small"
+	}
+	return &OpenAIEmbedder{
+		apiKey:  os.Getenv("OPENAI_API_KEY"),
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		model:   model,
+	}
+}
+
+func (o *OpenAIEmbedder) Model() string {
+	return o.model
+}
+
+func (o *OpenAIEmbedder) Embed(texts []string) ([][]float32, error) {
+	payload := map[string]interface{}{
+		"model": o.model,
+		"input": texts,
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequestWithContext(context.Background(),
+		"POST", o.baseURL+"/embeddings", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+o.apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		var errMsg map[string]interface{}
+		_ = json.NewDecoder(resp.Body).Decode(&errMsg)
+		return nil, fmt.Errorf("error %d: %+v", resp.StatusCode, errMsg)
+	}
+
+	var parsed struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+	if len(parsed.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(parsed.Data))
+	}
+
+	// The API may return items out of order; place each by its index
+	vectors := make([][]float32, len(texts))
+	for _, d := range parsed.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
@@ -1,6 +1,10 @@
 package llm
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+)
 
 // ResilientClient wraps an OpenAI client and falls back to MockClient if needed.
 type ResilientClient struct {
@@ -8,8 +12,14 @@ type ResilientClient struct {
 	mock   *MockClient
 }
 
-// Ensure ResilientClient implements LLM
-var _ LLM = (*ResilientClient)(nil)
+// Ensure ResilientClient implements LLM and the optional capabilities
+var (
+	_ LLM              = (*ResilientClient)(nil)
+	_ OptionsAnalyzer  = (*ResilientClient)(nil)
+	_ EntityExtractor  = (*ResilientClient)(nil)
+	_ QuestionAnswerer = (*ResilientClient)(nil)
+	_ ClusterLabeler   = (*ResilientClient)(nil)
+)
 
 // NewResilientClient returns a client that tries OpenAI first, then falls back to mock.
 func NewResilientClient(openai *OpenAIClient, mock *MockClient) *ResilientClient {
@@ -31,3 +41,44 @@ func (r *ResilientClient) AnalyzeText(input string) (
 	}
 	return summary, title, topics, sentiment, keywords, confidence, nil
 }
+
+// AnalyzeWithOptions applies the same fallback as AnalyzeText; the mock ignores the options
+func (r *ResilientClient) AnalyzeWithOptions(input string, opts AnalyzeOptions) (Result, error) {
+	res, err := r.openai.AnalyzeWithOptions(input, opts)
+	if err != nil {
+		fmt.Println("OpenAI request failed, falling back to MockClient:", err)
+		return Analyze(r.mock, input, opts)
+	}
+	return res, nil
+}
+
+// ExtractEntities tries OpenAI only; the mock has nothing useful to offer here,
+// so failures are returned and the caller falls back to local extraction
+func (r *ResilientClient) ExtractEntities(input string) ([]models.Entity, error) {
+	entities, err := r.openai.ExtractEntities(input)
+	if err != nil {
+		fmt.Println("OpenAI entity extraction failed:", err)
+		return nil, err
+	}
+	return entities, nil
+}
+
+// Answer tries OpenAI only; on failure the caller falls back to an extractive answer
+func (r *ResilientClient) Answer(question string, sources []Source) (string, []models.Citation, error) {
+	answer, citations, err := r.openai.Answer(question, sources)
+	if err != nil {
+		fmt.Println("OpenAI answer failed:", err)
+		return "", nil, err
+	}
+	return answer, citations, nil
+}
+
+// LabelCluster tries OpenAI only; on failure the caller labels the cluster with its terms
+func (r *ResilientClient) LabelCluster(terms []string, titles []string) (string, error) {
+	label, err := r.openai.LabelCluster(terms, titles)
+	if err != nil {
+		fmt.Println("OpenAI cluster labelling failed:", err)
+		return "", err
+	}
+	return label, nil
+}
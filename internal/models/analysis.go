@@ -6,13 +6,44 @@ import "time"
 // JSON tags enable automatic serialization for API responses
 // Fields are designed to capture key insights from unstructured text
 type Analysis struct {
-	ID         string    `json:"id"`         // UUID for unique identification and tracing
-	RawText    string    `json:"raw_text"`   // Original input text for reference
-	Summary    string    `json:"summary"`    // 1-2 sentence summary from LLM
-	Title      string    `json:"title"`      // Extracted or generated title
-	Topics     []string  `json:"topics"`     // 3 key topics identified by LLM
-	Sentiment  string    `json:"sentiment"`  // positive/neutral/negative classification
-	Keywords   []string  `json:"keywords"`   // 3 most frequent nouns (local extraction)
-	Confidence float64   `json:"confidence"` // Analysis confidence score (0-1)
-	CreatedAt  time.Time `json:"created_at"` // Timestamp for audit and sorting
+	ID         string     `json:"id"`                   // UUID for unique identification and tracing
+	RawText    string     `json:"raw_text"`             // Input text for reference, with personal data redacted when enabled
+	Summary    string     `json:"summary"`              // 1-2 sentence summary from LLM
+	Title      string     `json:"title"`                // Extracted or generated title
+	Topics     []string   `json:"topics"`               // 3 key topics identified by LLM
+	Sentiment  string     `json:"sentiment"`            // positive/neutral/negative classification
+	Keywords   []string   `json:"keywords"`             // 3 key words or phrases, extracted locally by default (see Sources)
+	Confidence float64    `json:"confidence"`           // Analysis confidence score (0-1), lowered when output is poorly grounded
+	Language   string     `json:"language"`             // Detected source language (ISO 639-1, "und" if unknown)
+	CreatedAt  time.Time  `json:"created_at"`           // Timestamp for audit and sorting
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"` // Last human edit through PATCH /analyses/{id}
+	Entities   []Entity   `json:"entities,omitempty"`   // Named entities with mention offsets
+
+	Provider string            `json:"provider,omitempty"` // LLM backend that produced the analysis ("openai", "mock")
+	Sources  map[string]string `json:"sources,omitempty"`  // Field name -> "llm", "local" or "merged"
+
+	Redactions []Redaction `json:"redactions,omitempty"` // Personal data replaced in raw_text (analyze response only)
+
+	InjectionSuspected bool     `json:"injection_suspected"`         // raw_text appears to address the model (prompt injection)
+	InjectionScore     float64  `json:"injection_score"`             // Injection likelihood (0-1)
+	InjectionSignals   []string `json:"injection_signals,omitempty"` // Detectors that fired (analyze response only)
+
+	Grounding *Grounding `json:"grounding,omitempty"` // Evidence for keywords, topics and summary in raw_text
+
+	KeywordScores []KeywordScore `json:"keyword_scores,omitempty"` // Local keywords weighted against the stored corpus
+
+	SentimentScore  float64          `json:"sentiment_score"`            // Lexicon compound score from -1 to 1 (0 without a lexicon)
+	SentimentScores *SentimentScores `json:"sentiment_scores,omitempty"` // Per-class lexicon scores and the cross-check against sentiment
+
+	TextStats *TextStats `json:"text_stats,omitempty"` // Length, lexical variety, readability and reading time
+
+	DuplicateOf string `json:"duplicate_of,omitempty"` // Canonical analysis this one is a near copy of
+
+	Pipeline []StageReport `json:"pipeline,omitempty"` // Outcome and duration of each analysis stage (analyze response only)
+}
+
+// AnalysisPage is one page of GET /analyses
+type AnalysisPage struct {
+	Analyses   []Analysis `json:"analyses"`
+	NextCursor string     `json:"next_cursor,omitempty"` // Pass as ?cursor= for the next page; empty on the last page
 }
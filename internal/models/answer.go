@@ -0,0 +1,26 @@
+package models
+
+// Answer is the response of POST /ask: an answer grounded in stored analyses
+type Answer struct {
+	Question  string         `json:"question"`
+	Answer    string         `json:"answer"`
+	Mode      string         `json:"mode"`      // "llm" or "extractive"
+	Citations []Citation     `json:"citations"` // Quoted spans backing the answer
+	Sources   []AnswerSource `json:"sources"`   // Retrieved analyses, best first
+}
+
+// Citation points at a quoted span of an analysis' raw text
+// Offsets are character (rune) positions in raw_text, end exclusive
+type Citation struct {
+	AnalysisID string `json:"analysis_id"`
+	Quote      string `json:"quote"`
+	Start      int    `json:"start"`
+	End        int    `json:"end"`
+}
+
+// AnswerSource is an analysis retrieved as context for an answer
+type AnswerSource struct {
+	AnalysisID string  `json:"analysis_id"`
+	Title      string  `json:"title"`
+	Score      float64 `json:"score"` // Retrieval score (BM25)
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Cluster is a theme shared by a group of stored analyses
+type Cluster struct {
+	ID          string          `json:"id"`
+	Label       string          `json:"label"`
+	LabelSource string          `json:"label_source"` // "llm" or "terms"
+	Terms       []string        `json:"terms"`        // Most characteristic terms, most central first
+	Size        int             `json:"size"`         // Member analyses
+	CreatedAt   time.Time       `json:"created_at"`   // When the clustering ran
+	Members     []ClusterMember `json:"members,omitempty"`
+}
+
+// ClusterMember is an analysis assigned to a cluster
+type ClusterMember struct {
+	AnalysisID string    `json:"analysis_id"`
+	Title      string    `json:"title"`
+	Summary    string    `json:"summary"`
+	Similarity float64   `json:"similarity"` // Cosine similarity to the cluster centroid (0-1)
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Duplicate is a stored analysis whose text is a near copy of another's
+type Duplicate struct {
+	AnalysisID  string    `json:"analysis_id"`
+	Title       string    `json:"title"`
+	Similarity  float64   `json:"similarity"`             // Estimated share of word pairs in common (0-1)
+	DuplicateOf string    `json:"duplicate_of,omitempty"` // Canonical analysis it was linked to on ingest
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import "strings"
+
+// Entity types recognised by the extractor
+// Anything outside this set is dropped before storage
+const (
+	EntityPerson       = "person"
+	EntityOrganization = "organization"
+	EntityLocation     = "location"
+	EntityProduct      = "product"
+	EntityDate         = "date"
+)
+
+// Entity is a named thing mentioned in an analysed document
+// The same canonical entity shares one ID across every document that mentions it
+type Entity struct {
+	ID       string    `json:"id"`       // Stable ID derived from type + canonical name
+	Type     string    `json:"type"`     // person/organization/location/product/date
+	Name     string    `json:"name"`     // Canonical display name
+	Mentions []Mention `json:"mentions"` // Every occurrence in the source text
+}
+
+// Mention is a single occurrence of an entity in the source text
+// Offsets are character (rune) positions, end exclusive
+type Mention struct {
+	Text  string `json:"text"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// EntityStat summarises an entity across the whole corpus for GET /entities
+type EntityStat struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Documents int    `json:"documents"` // Number of analyses mentioning the entity
+	Mentions  int    `json:"mentions"`  // Total mentions across all analyses
+}
+
+// NormalizeEntityType maps free-form type labels (e.g. from an LLM) onto the known set
+// Returns an empty string when the label is not recognised
+func NormalizeEntityType(t string) string {
+	switch strings.ToLower(strings.TrimSpace(t)) {
+	case "person", "per", "people":
+		return EntityPerson
+	case "organization", "organisation", "org", "company":
+		return EntityOrganization
+	case "location", "loc", "place", "gpe":
+		return EntityLocation
+	case "product":
+		return EntityProduct
+	case "date", "time":
+		return EntityDate
+	}
+	return ""
+}
@@ -0,0 +1,25 @@
+package models
+
+// Graph is a co-occurrence graph of concepts (topics, keywords, entities)
+// linked by the analyses they appear in together
+type Graph struct {
+	Documents int         `json:"documents"` // Analyses the graph was built from
+	Nodes     []GraphNode `json:"nodes"`
+	Edges     []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a concept; the same label across kinds is one node
+type GraphNode struct {
+	ID        string   `json:"id"` // Case-folded label
+	Label     string   `json:"label"`
+	Kinds     []string `json:"kinds"` // topic, keyword and/or an entity type
+	Documents int      `json:"documents"`
+}
+
+// GraphEdge links two concepts that appear in the same analyses
+type GraphEdge struct {
+	Source    string  `json:"source"`
+	Target    string  `json:"target"`
+	Documents int     `json:"documents"` // Analyses containing both
+	PMI       float64 `json:"pmi"`       // Pointwise mutual information, ln(p(a,b) / (p(a) p(b)))
+}
@@ -0,0 +1,32 @@
+package models
+
+// Grounding reports how well the LLM output is supported by the source text
+type Grounding struct {
+	Score         float64          `json:"score"`         // Share of the output supported by the text (0-1)
+	Hallucination bool             `json:"hallucination"` // Output makes claims the text does not support
+	Keywords      []TermGrounding  `json:"keywords"`
+	Topics        []TermGrounding  `json:"topics"`
+	Summary       []ClaimGrounding `json:"summary"`
+}
+
+// TermGrounding ties a keyword or topic to where it occurs in the source
+type TermGrounding struct {
+	Term     string `json:"term"`
+	Grounded bool   `json:"grounded"`
+	Evidence []Span `json:"evidence"` // Occurrences in raw_text
+}
+
+// ClaimGrounding measures one summary sentence against the source
+type ClaimGrounding struct {
+	Sentence string  `json:"sentence"`
+	Overlap  float64 `json:"overlap"` // Share of the sentence's content words found in the source (0-1)
+	Grounded bool    `json:"grounded"`
+	Evidence *Span   `json:"evidence,omitempty"` // Source sentence sharing the most content words
+}
+
+// Span is a piece of the source text; offsets are character (rune) positions, end exclusive
+type Span struct {
+	Text  string `json:"text"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
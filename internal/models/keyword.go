@@ -0,0 +1,10 @@
+package models
+
+// KeywordScore is a keyword weighted by how distinctive it is for one document
+// compared with the rest of the stored corpus
+type KeywordScore struct {
+	Keyword   string  `json:"keyword"`
+	Score     float64 `json:"score"`     // TF-IDF or BM25 weight; higher is more distinctive
+	Count     int     `json:"count"`     // Occurrences in the document, inflected forms included
+	Documents int     `json:"documents"` // Stored documents containing the term, this one included
+}
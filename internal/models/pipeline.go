@@ -0,0 +1,10 @@
+package models
+
+// StageReport records how one stage of the analysis pipeline went
+type StageReport struct {
+	Stage      string  `json:"stage"`
+	Status     string  `json:"status"` // ok, skipped, degraded, failed or disabled
+	Policy     string  `json:"policy"` // Error policy in effect: fail, skip or degrade
+	DurationMs float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
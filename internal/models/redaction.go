@@ -0,0 +1,10 @@
+package models
+
+// Redaction records personal data replaced before analysis
+// Offsets are character (rune) positions in the stored raw_text, end exclusive
+type Redaction struct {
+	Kind        string `json:"kind"`        // email/phone/card/iban/ip/person
+	Start       int    `json:"start"`       // Start of the replacement
+	End         int    `json:"end"`         // End of the replacement
+	Replacement string `json:"replacement"` // "[EMAIL]", "[EMAIL:9f86d081]" or "[EMAIL_1]"
+}
@@ -0,0 +1,34 @@
+package models
+
+// SearchResult is an analysis returned by a ranked search, with its score
+// The analysis fields are inlined so results look like plain analyses plus "score"
+type SearchResult struct {
+	Analysis
+	Score       float64            `json:"score"`                 // Similarity or relevance score; higher is better
+	Snippet     string             `json:"snippet,omitempty"`     // Passage of raw_text with matches in <mark> tags (full-text search)
+	Explanation *SearchExplanation `json:"explanation,omitempty"` // Why the hit ranked where it did (hybrid search)
+}
+
+// SearchPage is one page of GET /search
+type SearchPage struct {
+	Results    []SearchResult `json:"results"`
+	Total      int            `json:"total"`                 // Matches across all pages
+	NextCursor string         `json:"next_cursor,omitempty"` // Pass as ?cursor= for the next page; empty on the last page
+}
+
+// SearchExplanation breaks a fused score down by retriever
+type SearchExplanation struct {
+	Method        string             `json:"method"`                  // Fusion method, e.g. "rrf"
+	K             float64            `json:"k"`                       // RRF rank constant
+	Contributions []RankContribution `json:"contributions"`           // One entry per retriever that returned the hit
+	MatchedTerms  []string           `json:"matched_terms,omitempty"` // Query terms found in the analysis
+}
+
+// RankContribution is one retriever's part of a fused score
+type RankContribution struct {
+	Retriever    string  `json:"retriever"`    // "keyword" or "vector"
+	Rank         int     `json:"rank"`         // 1-based rank within that retriever
+	Score        float64 `json:"score"`        // The retriever's own score (BM25/ts_rank or cosine)
+	Weight       float64 `json:"weight"`       // Retriever weight
+	Contribution float64 `json:"contribution"` // weight / (k + rank)
+}
@@ -0,0 +1,12 @@
+package models
+
+// SentimentScores is the offline lexicon-based sentiment of a text
+// Class scores are shares of the text's sentiment-bearing weight and sum to 1
+type SentimentScores struct {
+	Compound float64 `json:"compound"` // Overall polarity from -1 (most negative) to 1 (most positive)
+	Positive float64 `json:"positive"`
+	Neutral  float64 `json:"neutral"`
+	Negative float64 `json:"negative"`
+	Label    string  `json:"label"`              // positive/neutral/negative derived from Compound
+	Conflict bool    `json:"conflict,omitempty"` // The stored sentiment has the opposite polarity
+}
@@ -0,0 +1,15 @@
+package models
+
+// TextStats are document statistics computed locally from the analysed text
+// Readability formulas are calibrated for English and are omitted for other languages
+type TextStats struct {
+	Characters         int      `json:"characters"` // Non-whitespace characters
+	Words              int      `json:"words"`      // Chinese and Japanese characters count as one word each
+	Sentences          int      `json:"sentences"`
+	Paragraphs         int      `json:"paragraphs"`             // Blocks separated by blank lines
+	AvgSentenceLength  float64  `json:"avg_sentence_length"`    // Words per sentence
+	TypeTokenRatio     float64  `json:"type_token_ratio"`       // Distinct words / words (0-1), lexical variety
+	ReadingEase        *float64 `json:"reading_ease,omitempty"` // Flesch reading ease, higher is easier (roughly 0-100)
+	GradeLevel         *float64 `json:"grade_level,omitempty"`  // Flesch-Kincaid US school grade
+	ReadingTimeSeconds int      `json:"reading_time_seconds"`   // Estimated silent reading time
+}
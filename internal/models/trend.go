@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Trends is the response of GET /trends: term and sentiment activity over time
+type Trends struct {
+	Interval string        `json:"interval"`  // hour, day or week
+	TimeZone string        `json:"time_zone"` // IANA name bucket boundaries are computed in
+	Field    string        `json:"field"`     // topics, keywords or both
+	From     time.Time     `json:"from"`      // Start of the first bucket
+	To       time.Time     `json:"to"`        // End of the range (exclusive)
+	Buckets  []TrendBucket `json:"buckets"`   // Every bucket in the range, oldest first, empty ones included
+	Rising   []TermChange  `json:"rising"`    // Terms gaining the most versus the previous period of equal length
+	Falling  []TermChange  `json:"falling"`   // Terms losing the most versus the previous period
+}
+
+// TrendBucket counts the analyses created in one time bucket
+type TrendBucket struct {
+	Start             time.Time       `json:"start"`
+	Documents         int             `json:"documents"`
+	Sentiment         SentimentCounts `json:"sentiment"`
+	AvgSentimentScore float64         `json:"avg_sentiment_score"` // Mean lexicon compound score (0 when empty)
+	Terms             []TermCount     `json:"terms"`               // Most frequent terms, most frequent first
+}
+
+// SentimentCounts is a sentiment distribution; Unknown counts analyses without a label
+type SentimentCounts struct {
+	Positive int `json:"positive"`
+	Neutral  int `json:"neutral"`
+	Negative int `json:"negative"`
+	Unknown  int `json:"unknown"`
+}
+
+// TermCount is the number of analyses listing a topic or keyword
+type TermCount struct {
+	Term  string `json:"term"`
+	Count int    `json:"count"`
+}
+
+// TermChange compares a term's document count between two periods
+type TermChange struct {
+	Term     string  `json:"term"`
+	Previous int     `json:"previous"`
+	Current  int     `json:"current"`
+	Change   int     `json:"change"` // Current - Previous
+	Growth   float64 `json:"growth"` // Change relative to Previous (new terms count Previous as 1)
+}
@@ -0,0 +1,66 @@
+package redact
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ConfigFromEnv reads the redaction settings:
+//
+//	REDACT_POLICY   policy spec, see ParsePolicies; empty disables redaction
+//	REDACT_HASH_KEY secret for the hash policy
+//	REDACT_MAP_KEY  base64 AES-128/192/256 key encrypting token maps
+//
+// ok is false when redaction is disabled
+func ConfigFromEnv() (cfg Config, ok bool, err error) {
+	spec := strings.TrimSpace(os.Getenv("REDACT_POLICY"))
+	if spec == "" {
+		return Config{}, false, nil
+	}
+	cfg.Policies, err = ParsePolicies(spec)
+	if err != nil {
+		return Config{}, false, err
+	}
+	cfg.HashKey = []byte(os.Getenv("REDACT_HASH_KEY"))
+	if key := os.Getenv("REDACT_MAP_KEY"); key != "" {
+		cfg.MapKey, err = base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return Config{}, false, fmt.Errorf("REDACT_MAP_KEY must be base64: %w", err)
+		}
+	}
+	return cfg, true, nil
+}
+
+// ParsePolicies parses a comma-separated policy spec
+// A bare policy applies to every kind; kind=policy entries override it:
+//
+//	"mask"                       mask everything
+//	"tokenize,person=mask"       tokenize everything except names, which are masked
+//	"email=hash,phone=hash"      only emails and phone numbers
+func ParsePolicies(spec string) (map[Kind]Policy, error) {
+	policies := make(map[Kind]Policy)
+	overrides := make(map[Kind]Policy)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		kind, policy, scoped := strings.Cut(item, "=")
+		if !scoped {
+			for _, k := range Kinds {
+				policies[k] = Policy(kind)
+			}
+			continue
+		}
+		overrides[Kind(strings.TrimSpace(kind))] = Policy(strings.TrimSpace(policy))
+	}
+	for k, p := range overrides {
+		policies[k] = p
+	}
+	if len(policies) == 0 {
+		return nil, fmt.Errorf("empty redaction policy")
+	}
+	return policies, nil
+}
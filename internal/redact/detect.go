@@ -0,0 +1,249 @@
+package redact
+
+import (
+	"math/big"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+)
+
+// Kind is a category of personal data
+type Kind string
+
+const (
+	KindEmail  Kind = "email"
+	KindPhone  Kind = "phone"
+	KindCard   Kind = "card"
+	KindIBAN   Kind = "iban"
+	KindIP     Kind = "ip"
+	KindPerson Kind = "person"
+)
+
+// Kinds lists every detectable kind, in the order overlaps are resolved
+// (a card number is also a run of digits that looks like a phone number)
+var Kinds = []Kind{KindEmail, KindCard, KindIBAN, KindIP, KindPhone, KindPerson}
+
+// Finding is a piece of personal data located in a text
+// Offsets are byte positions, end exclusive
+type Finding struct {
+	Kind  Kind
+	Start int
+	End   int
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)
+	// 13-19 digits, in one run or in groups of four (Amex: 4-6-5) with one kind of separator
+	cardPattern = regexp.MustCompile(`\b(?:\d{13,19}|\d{4}(?: \d{4}){2} \d{1,7}|\d{4}(?:-\d{4}){2}-\d{1,7}|\d{4} \d{6} \d{5}|\d{4}-\d{6}-\d{5})\b`)
+	// Country code, check digits, then 11-30 alphanumerics in one run or in groups of four
+	ibanPattern = regexp.MustCompile(`\b[A-Z]{2}\d{2}(?:[A-Z0-9]{11,30}|(?: [A-Z0-9]{4}){2,7}(?: [A-Z0-9]{1,4})?)\b`)
+	ipv4Pattern = regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}\b`)
+	ipv6Pattern = regexp.MustCompile(`(?i)(?:[0-9a-f]{0,4}:){2,7}[0-9a-f]{0,4}`)
+	// +country code and/or (area code), then digit groups; otherwise a first group and
+	// groups of 2-4 digits. Groups are split by at most one space, dot or dash
+	phonePattern = regexp.MustCompile(`(?:\+\d{1,3}(?:[ .-]?\(\d{1,5}\))?|\(\d{1,5}\))(?:[ .-]?\d{2,8}){1,5}\b|\b\d{1,5}(?:[ .-]\d{2,4}){1,5}\b`)
+	// A date starting a phone-like run ("2024-01-15 12345"), with the separator after it
+	datePrefix = regexp.MustCompile(`^\d{4}[-./]\d{1,2}[-./]\d{1,2}(?:[ .-]|$)`)
+)
+
+// Detect finds personal data of the given kinds in text, ordered by position
+// Overlapping findings are resolved in favour of the earliest, then longest,
+// then the kind listed first in Kinds
+func Detect(text string, kinds []Kind) []Finding {
+	var found []Finding
+	for _, kind := range kinds {
+		switch kind {
+		case KindEmail:
+			found = appendMatches(found, kind, text, emailPattern, nil)
+		case KindCard:
+			found = appendMatches(found, kind, text, cardPattern, cardValid)
+		case KindIBAN:
+			found = appendMatches(found, kind, text, ibanPattern, ibanValid)
+		case KindIP:
+			found = appendMatches(found, kind, text, ipv4Pattern, isIP)
+			found = appendMatches(found, kind, text, ipv6Pattern, func(s string) bool {
+				return strings.ContainsAny(s, "0123456789abcdefABCDEF") && isIP(s)
+			})
+		case KindPhone:
+			found = append(found, phoneFindings(text)...)
+		case KindPerson:
+			found = append(found, personFindings(text)...)
+		}
+	}
+	return resolveOverlaps(found)
+}
+
+func appendMatches(found []Finding, kind Kind, text string, pattern *regexp.Regexp, valid func(string) bool) []Finding {
+	for _, m := range pattern.FindAllStringIndex(text, -1) {
+		if valid == nil || valid(text[m[0]:m[1]]) {
+			found = append(found, Finding{Kind: kind, Start: m[0], End: m[1]})
+		}
+	}
+	return found
+}
+
+// phoneFindings matches phonePattern, leaving out a leading date and numbers that
+// continue a longer token ("ORD-555-123-4567", "v2.555.123.4567")
+func phoneFindings(text string) []Finding {
+	var found []Finding
+	for _, m := range phonePattern.FindAllStringIndex(text, -1) {
+		start, end := m[0], m[1]
+		if start > 0 && strings.ContainsRune("-./_#", rune(text[start-1])) {
+			continue
+		}
+		if d := datePrefix.FindStringIndex(text[start:end]); d != nil {
+			start += d[1]
+		}
+		if start < end && phoneValid(text[start:end]) {
+			found = append(found, Finding{Kind: KindPhone, Start: start, End: end})
+		}
+	}
+	return found
+}
+
+// personFindings reuses the rule-based entity extractor, whose mentions are rune offsets
+func personFindings(text string) []Finding {
+	var found []Finding
+	var byteAt []int
+	for _, e := range analyzer.ExtractEntities(text) {
+		if e.Type != models.EntityPerson {
+			continue
+		}
+		if byteAt == nil {
+			byteAt = byteOffsets(text)
+		}
+		for _, m := range e.Mentions {
+			found = append(found, Finding{Kind: KindPerson, Start: byteAt[m.Start], End: byteAt[m.End]})
+		}
+	}
+	return found
+}
+
+// byteOffsets maps every rune offset in text (including the end) to a byte offset
+func byteOffsets(text string) []int {
+	offsets := make([]int, 0, utf8.RuneCountInString(text)+1)
+	for i := range text {
+		offsets = append(offsets, i)
+	}
+	return append(offsets, len(text))
+}
+
+func resolveOverlaps(found []Finding) []Finding {
+	priority := make(map[Kind]int, len(Kinds))
+	for i, k := range Kinds {
+		priority[k] = i
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		if a.End-a.Start != b.End-b.Start {
+			return a.End-a.Start > b.End-b.Start
+		}
+		return priority[a.Kind] < priority[b.Kind]
+	})
+
+	var kept []Finding
+	end := 0
+	for _, f := range found {
+		if f.Start < end {
+			continue
+		}
+		kept = append(kept, f)
+		end = f.End
+	}
+	return kept
+}
+
+func digitsOnly(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// cardValid accepts numbers of the issuers' ranges (2-6: Mastercard, Amex and Diners,
+// Visa, Mastercard, Discover and UnionPay) that pass the Luhn checksum
+func cardValid(s string) bool {
+	digits := digitsOnly(s)
+	return digits != "" && digits[0] >= '2' && digits[0] <= '6' && luhnValid(digits)
+}
+
+// luhnValid implements the Luhn checksum used by payment card numbers
+func luhnValid(digits string) bool {
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// ibanLengths are the IBAN lengths of the countries in the ISO 13616 registry
+var ibanLengths = map[string]int{
+	"AD": 24, "AE": 23, "AL": 28, "AT": 20, "AZ": 28, "BA": 20, "BE": 16, "BG": 22, "BH": 22, "BR": 29,
+	"BY": 28, "CH": 21, "CR": 22, "CY": 28, "CZ": 24, "DE": 22, "DK": 18, "DO": 28, "EE": 20, "EG": 29,
+	"ES": 24, "FI": 18, "FO": 18, "FR": 27, "GB": 22, "GE": 22, "GI": 23, "GL": 18, "GR": 27, "GT": 28,
+	"HR": 21, "HU": 28, "IE": 22, "IL": 23, "IQ": 23, "IS": 26, "IT": 27, "JO": 30, "KW": 30, "KZ": 20,
+	"LB": 28, "LC": 32, "LI": 21, "LT": 20, "LU": 20, "LV": 21, "MC": 27, "MD": 24, "ME": 22, "MK": 19,
+	"MR": 27, "MT": 31, "MU": 30, "NL": 18, "NO": 15, "PK": 24, "PL": 28, "PS": 29, "PT": 25, "QA": 29,
+	"RO": 24, "RS": 22, "SA": 24, "SC": 31, "SE": 24, "SI": 19, "SK": 24, "SM": 27, "ST": 25, "SV": 28,
+	"TL": 23, "TN": 24, "TR": 26, "UA": 29, "VA": 22, "VG": 24, "XK": 20,
+}
+
+// ibanValid checks the country's IBAN length and the ISO 13616 mod-97 checksum
+// Algorithm: move the first four characters to the end -> letters become 10..35 -> remainder must be 1
+func ibanValid(s string) bool {
+	iban := strings.ReplaceAll(s, " ", "")
+	if len(iban) != ibanLengths[iban[:2]] {
+		return false
+	}
+	var b strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			b.WriteString(strconv.Itoa(int(r-'A') + 10))
+		default:
+			return false
+		}
+	}
+	n, ok := new(big.Int).SetString(b.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// phoneValid accepts 7-15 digits (E.164) when written with a leading + or (,
+// and otherwise at least 9 so that times and short ranges are not phones
+func phoneValid(s string) bool {
+	n := len(digitsOnly(s))
+	if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "(") {
+		return n >= 7 && n <= 15
+	}
+	return n >= 9 && n <= 15
+}
+
+func isIP(s string) bool {
+	return net.ParseIP(s) != nil
+}
@@ -0,0 +1,60 @@
+package redact
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		kind Kind
+		text string
+		want []string
+	}{
+		{"international phone", KindPhone, "Call +44 20 7946 0958 today", []string{"+44 20 7946 0958"}},
+		{"phone with area code", KindPhone, "Office: (555) 123-4567.", []string{"(555) 123-4567"}},
+		{"country code and area code", KindPhone, "Reach me at +1 (555) 123-4567", []string{"+1 (555) 123-4567"}},
+		{"dashed phone", KindPhone, "Cell 555-123-4567", []string{"555-123-4567"}},
+		{"grouped in pairs", KindPhone, "Tel 01 23 45 67 89", []string{"01 23 45 67 89"}},
+		{"E.164 without separators", KindPhone, "+4915112345678", []string{"+4915112345678"}},
+		{"phone after a date", KindPhone, "On 2024-01-15 555 123 4567 called", []string{"555 123 4567"}},
+		{"date then number", KindPhone, "Shipped 2024-01-15 12345 units", nil},
+		{"date then time", KindPhone, "Logged at 2024-01-15 10 30 45", nil},
+		{"order number", KindPhone, "Order 123456789 was shipped", nil},
+		{"prefixed order number", KindPhone, "Ref ORD-2024-000123 and INV-555-123-4567", nil},
+		{"invoice number", KindPhone, "Invoice 2024-000123 is overdue", nil},
+		{"version numbers", KindPhone, "Upgrade from 1.2.3 to 10.4.12.2024", nil},
+		{"double separators", KindPhone, "Scores 555 - 123 - 4567", nil},
+		{"quantities", KindPhone, "Sold 12 boxes, 300 kg and 45 crates", nil},
+
+		{"grouped card", KindCard, "Card 4111 1111 1111 1111 on file", []string{"4111 1111 1111 1111"}},
+		{"dashed card", KindCard, "Card 5500-0000-0000-0004", []string{"5500-0000-0000-0004"}},
+		{"amex card", KindCard, "Amex 3782 822463 10005", []string{"3782 822463 10005"}},
+		{"card run", KindCard, "4111111111111111", []string{"4111111111111111"}},
+		{"failing checksum", KindCard, "Card 4111 1111 1111 1112", nil},
+		{"checksum outside issuer ranges", KindCard, "Tracking 1234567812345670", nil},
+		{"mixed separators", KindCard, "4111 1111-1111 1111", nil},
+		{"irregular groups", KindCard, "Parts 41 1111 11 1111 1111", nil},
+
+		{"grouped IBAN", KindIBAN, "Pay GB82 WEST 1234 5698 7654 32 now", []string{"GB82 WEST 1234 5698 7654 32"}},
+		{"IBAN run", KindIBAN, "DE89370400440532013000", []string{"DE89370400440532013000"}},
+		{"failing checksum", KindIBAN, "Pay GB82 WEST 1234 5698 7654 33", nil},
+		{"wrong country length", KindIBAN, "GB82 WEST 1234 5698 7654 3", nil},
+		{"unknown country", KindIBAN, "ZZ82 WEST 1234 5698 7654 32", nil},
+		{"capitalised words", KindIBAN, "AB12 THIS TEXT WILL PASS", nil},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.kind)+"/"+tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range Detect(tt.text, []Kind{tt.kind}) {
+				got = append(got, tt.text[f.Start:f.End])
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Detect(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Detect(%q) = %q, want %q", tt.text, got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+)
+
+// Policy decides what replaces a piece of personal data
+type Policy string
+
+const (
+	// PolicyMask replaces the value with its kind: "[EMAIL]"
+	PolicyMask Policy = "mask"
+	// PolicyHash replaces the value with a keyed hash, so equal values stay
+	// linkable across documents without being recoverable: "[EMAIL:9f86d081]"
+	PolicyHash Policy = "hash"
+	// PolicyTokenize replaces the value with a per-document token, "[EMAIL_1]";
+	// the token map is kept (encrypted) so authorized users can re-identify
+	PolicyTokenize Policy = "tokenize"
+)
+
+// Config selects which kinds are redacted and how
+type Config struct {
+	Policies map[Kind]Policy // Kinds without a policy are left as is
+	HashKey  []byte          // HMAC key for PolicyHash
+	MapKey   []byte          // AES key (16, 24 or 32 bytes) encrypting token maps for PolicyTokenize
+}
+
+// Redactor applies a Config to texts
+type Redactor struct {
+	policies map[Kind]Policy
+	kinds    []Kind
+	hashKey  []byte
+	vault    *Vault
+}
+
+// New validates cfg and returns a Redactor
+// Hashing requires a HashKey (an unkeyed hash of an email is trivially reversible
+// by guessing); tokenizing requires a MapKey to encrypt the stored maps
+func New(cfg Config) (*Redactor, error) {
+	r := &Redactor{policies: make(map[Kind]Policy), hashKey: cfg.HashKey}
+	for _, kind := range Kinds {
+		policy, ok := cfg.Policies[kind]
+		if !ok {
+			continue
+		}
+		switch policy {
+		case PolicyMask:
+		case PolicyHash:
+			if len(cfg.HashKey) == 0 {
+				return nil, fmt.Errorf("redact: %s policy for %s needs a hash key", policy, kind)
+			}
+		case PolicyTokenize:
+			if r.vault == nil {
+				vault, err := NewVault(cfg.MapKey)
+				if err != nil {
+					return nil, fmt.Errorf("redact: %s policy for %s: %w", policy, kind, err)
+				}
+				r.vault = vault
+			}
+		default:
+			return nil, fmt.Errorf("redact: unknown policy %q for %s", policy, kind)
+		}
+		r.policies[kind] = policy
+		r.kinds = append(r.kinds, kind)
+	}
+	for kind := range cfg.Policies {
+		if _, ok := r.policies[kind]; !ok {
+			return nil, fmt.Errorf("redact: unknown kind %q", kind)
+		}
+	}
+	return r, nil
+}
+
+// Result is a redacted text with what was replaced
+type Result struct {
+	Text       string
+	Redactions []models.Redaction // Rune offsets into Text
+	Tokens     map[string]string  // Token -> original value, PolicyTokenize only
+}
+
+// Redact replaces the configured kinds of personal data in text
+// Repeated values get the same replacement within a document
+func (r *Redactor) Redact(text string) Result {
+	res := Result{Redactions: []models.Redaction{}}
+	findings := Detect(text, r.kinds)
+	if len(findings) == 0 {
+		res.Text = text
+		return res
+	}
+
+	tokenFor := make(map[string]string)
+	counters := make(map[Kind]int)
+	var b strings.Builder
+	last, runes := 0, 0
+	for _, f := range findings {
+		b.WriteString(text[last:f.Start])
+		runes += utf8.RuneCountInString(text[last:f.Start])
+		original := text[f.Start:f.End]
+
+		var replacement string
+		label := strings.ToUpper(string(f.Kind))
+		switch r.policies[f.Kind] {
+		case PolicyMask:
+			replacement = "[" + label + "]"
+		case PolicyHash:
+			mac := hmac.New(sha256.New, r.hashKey)
+			mac.Write([]byte(string(f.Kind) + ":" + normalizeValue(f.Kind, original)))
+			replacement = "[" + label + ":" + hex.EncodeToString(mac.Sum(nil)[:4]) + "]"
+		case PolicyTokenize:
+			key := string(f.Kind) + ":" + normalizeValue(f.Kind, original)
+			if tok, ok := tokenFor[key]; ok {
+				replacement = tok
+			} else {
+				counters[f.Kind]++
+				replacement = fmt.Sprintf("[%s_%d]", label, counters[f.Kind])
+				tokenFor[key] = replacement
+				if res.Tokens == nil {
+					res.Tokens = make(map[string]string)
+				}
+				res.Tokens[replacement] = original
+			}
+		}
+
+		n := utf8.RuneCountInString(replacement)
+		res.Redactions = append(res.Redactions, models.Redaction{
+			Kind: string(f.Kind), Start: runes, End: runes + n, Replacement: replacement,
+		})
+		b.WriteString(replacement)
+		runes += n
+		last = f.End
+	}
+	b.WriteString(text[last:])
+	res.Text = b.String()
+	return res
+}
+
+// normalizeValue makes formatting variants of one value redact identically
+// ("+44 20 7946 0958" and "+442079460958", "Jane@Example.com" and "jane@example.com")
+func normalizeValue(kind Kind, value string) string {
+	switch kind {
+	case KindPhone, KindCard:
+		return digitsOnly(value)
+	case KindIBAN:
+		return strings.ReplaceAll(value, " ", "")
+	case KindEmail, KindIP:
+		return strings.ToLower(value)
+	}
+	return value
+}
+
+// Restore puts the original values back in place of their tokens
+// It also works on LLM output (summaries, titles) that repeats the tokens
+func Restore(text string, tokens map[string]string) string {
+	if len(tokens) == 0 {
+		return text
+	}
+	keys := make([]string, 0, len(tokens))
+	for tok := range tokens {
+		keys = append(keys, tok)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, 2*len(keys))
+	for _, tok := range keys {
+		pairs = append(pairs, tok, tokens[tok])
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// Tokenizes reports whether any kind uses PolicyTokenize, i.e. whether Seal/Open are usable
+func (r *Redactor) Tokenizes() bool {
+	return r.vault != nil
+}
+
+// SealTokens encrypts a token map for storage, bound to the analysis it belongs to
+func (r *Redactor) SealTokens(analysisID string, tokens map[string]string) ([]byte, error) {
+	if r.vault == nil {
+		return nil, fmt.Errorf("redact: tokenization is not configured")
+	}
+	return r.vault.Seal(analysisID, tokens)
+}
+
+// OpenTokens decrypts a token map sealed by SealTokens for the same analysis
+func (r *Redactor) OpenTokens(analysisID string, sealed []byte) (map[string]string, error) {
+	if r.vault == nil {
+		return nil, fmt.Errorf("redact: tokenization is not configured")
+	}
+	return r.vault.Open(analysisID, sealed)
+}
@@ -0,0 +1,60 @@
+package redact
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+)
+
+// Vault encrypts token maps with AES-GCM
+// The analysis ID is authenticated as additional data, so a sealed map copied
+// onto another analysis fails to open instead of re-identifying the wrong text
+type Vault struct {
+	aead cipher.AEAD
+}
+
+func NewVault(key []byte) (*Vault, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("missing encryption key")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Vault{aead: aead}, nil
+}
+
+// Seal returns nonce || ciphertext of the JSON-encoded map
+func (v *Vault) Seal(analysisID string, tokens map[string]string) ([]byte, error) {
+	plaintext, err := json.Marshal(tokens)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, v.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return v.aead.Seal(nonce, nonce, plaintext, []byte(analysisID)), nil
+}
+
+func (v *Vault) Open(analysisID string, sealed []byte) (map[string]string, error) {
+	n := v.aead.NonceSize()
+	if len(sealed) < n {
+		return nil, fmt.Errorf("redact: sealed map too short")
+	}
+	plaintext, err := v.aead.Open(nil, sealed[:n], sealed[n:], []byte(analysisID))
+	if err != nil {
+		return nil, fmt.Errorf("redact: cannot decrypt token map: %w", err)
+	}
+	var tokens map[string]string
+	if err := json.Unmarshal(plaintext, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
@@ -0,0 +1,74 @@
+package search
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+)
+
+// NoAnswer is returned by ExtractiveAnswer when no sentence matches the question
+const NoAnswer = "No answer was found in the stored analyses."
+
+// ExtractiveAnswer answers without an LLM by quoting the sentences that best
+// cover the question terms. docs must be ordered best first; earlier documents
+// win ties. Citations reference the quoted sentences by rune offset
+func ExtractiveAnswer(terms []string, lang string, docs []Document, maxSentences int) (string, []models.Citation) {
+	type scored struct {
+		doc      int
+		sentence analyzer.Sentence
+		score    float64
+	}
+
+	// STEP 1: Score every sentence by the share of question terms it contains,
+	// with a small bonus for sentences from higher-ranked documents
+	var candidates []scored
+	for di, d := range docs {
+		for _, sent := range analyzer.SplitSentences(d.Text) {
+			present := make(map[string]bool)
+			for _, tok := range analyzer.Tokenize(sent.Text, lang) {
+				present[tok] = true
+			}
+			matched := 0
+			for _, t := range terms {
+				if present[t] {
+					matched++
+				}
+			}
+			if matched == 0 {
+				continue
+			}
+			score := float64(matched)/float64(len(terms)) + 0.1/float64(di+1)
+			candidates = append(candidates, scored{di, sent, score})
+		}
+	}
+	if len(candidates) == 0 {
+		return NoAnswer, []models.Citation{}
+	}
+
+	// STEP 2: Take the best sentences, then present them in document/position order
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	if len(candidates) > maxSentences {
+		candidates = candidates[:maxSentences]
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].doc != candidates[j].doc {
+			return candidates[i].doc < candidates[j].doc
+		}
+		return candidates[i].sentence.Start < candidates[j].sentence.Start
+	})
+
+	parts := make([]string, 0, len(candidates))
+	citations := make([]models.Citation, 0, len(candidates))
+	for _, c := range candidates {
+		parts = append(parts, c.sentence.Text)
+		citations = append(citations, models.Citation{
+			AnalysisID: docs[c.doc].ID,
+			Quote:      c.sentence.Text,
+			Start:      c.sentence.Start,
+			End:        c.sentence.End,
+		})
+	}
+	return strings.Join(parts, " "), citations
+}
@@ -0,0 +1,81 @@
+package search
+
+import (
+	"sort"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+)
+
+// DefaultRRFK is the rank constant from the original RRF paper (Cormack et al., 2009)
+// Larger values flatten the difference between top and lower ranks
+const DefaultRRFK = 60
+
+// RankedList is one retriever's results, best first
+type RankedList struct {
+	Name   string  // Retriever name, e.g. "keyword" or "vector"
+	Weight float64 // Multiplier for this retriever's RRF contributions
+	Hits   []Hit
+}
+
+// FusedHit is a document ranked by reciprocal rank fusion, with a per-retriever breakdown
+type FusedHit struct {
+	ID            string
+	Score         float64
+	Contributions []models.RankContribution
+}
+
+// FuseRRF merges ranked lists with weighted reciprocal rank fusion:
+// score(d) = Σ weight_r / (k + rank_r(d)), summed over the retrievers that returned d
+// Only ranks matter, so retrievers with incomparable scores (BM25 vs cosine) combine cleanly
+func FuseRRF(lists []RankedList, k float64) []FusedHit {
+	if k <= 0 {
+		k = DefaultRRFK
+	}
+
+	index := make(map[string]int)
+	var fused []FusedHit
+	for _, list := range lists {
+		for i, h := range list.Hits {
+			rank := i + 1
+			contribution := list.Weight / (k + float64(rank))
+
+			j, ok := index[h.ID]
+			if !ok {
+				j = len(fused)
+				index[h.ID] = j
+				fused = append(fused, FusedHit{ID: h.ID})
+			}
+			fused[j].Score += contribution
+			fused[j].Contributions = append(fused[j].Contributions, models.RankContribution{
+				Retriever:    list.Name,
+				Rank:         rank,
+				Score:        h.Score,
+				Weight:       list.Weight,
+				Contribution: contribution,
+			})
+		}
+	}
+
+	// Best fused score first; ties go to the better single rank, then ID for stability
+	sort.SliceStable(fused, func(i, j int) bool {
+		if fused[i].Score != fused[j].Score {
+			return fused[i].Score > fused[j].Score
+		}
+		bi, bj := bestRank(fused[i]), bestRank(fused[j])
+		if bi != bj {
+			return bi < bj
+		}
+		return fused[i].ID < fused[j].ID
+	})
+	return fused
+}
+
+func bestRank(h FusedHit) int {
+	best := 0
+	for _, c := range h.Contributions {
+		if best == 0 || c.Rank < best {
+			best = c.Rank
+		}
+	}
+	return best
+}
@@ -0,0 +1,98 @@
+package search
+
+import (
+	"math"
+	"sort"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
+)
+
+// Document is a unit of retrievable text, usually one stored analysis
+type Document struct {
+	ID   string
+	Text string
+}
+
+// Hit is a ranked document
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+// Okapi BM25 defaults
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// QueryTerms tokenizes a query in its detected language and drops stopwords
+// Duplicate terms are kept once, in first-occurrence order
+func QueryTerms(query string) (terms []string, lang string) {
+	lang, _ = analyzer.DetectLanguage(query)
+	seen := make(map[string]bool)
+	for _, tok := range analyzer.Tokenize(query, lang) {
+		if analyzer.IsStopword(tok, lang) || seen[tok] {
+			continue
+		}
+		seen[tok] = true
+		terms = append(terms, tok)
+	}
+	return terms, lang
+}
+
+// RankBM25 scores docs against terms with Okapi BM25 and returns the matching
+// documents best first. IDF is computed over docs themselves, so callers should
+// pass a reasonably sized candidate set rather than a single document
+func RankBM25(terms []string, lang string, docs []Document) []Hit {
+	if len(terms) == 0 || len(docs) == 0 {
+		return nil
+	}
+
+	// STEP 1: Term frequencies per document and document frequencies per term
+	tfs := make([]map[string]int, len(docs))
+	lengths := make([]int, len(docs))
+	df := make(map[string]int)
+	totalLen := 0
+	for i, d := range docs {
+		tokens := analyzer.Tokenize(d.Text, lang)
+		tf := make(map[string]int)
+		for _, tok := range tokens {
+			tf[tok]++
+		}
+		for _, t := range terms {
+			if tf[t] > 0 {
+				df[t]++
+			}
+		}
+		tfs[i] = tf
+		lengths[i] = len(tokens)
+		totalLen += len(tokens)
+	}
+	avgLen := float64(totalLen) / float64(len(docs))
+	if avgLen == 0 {
+		avgLen = 1
+	}
+
+	// STEP 2: BM25 score per document
+	n := float64(len(docs))
+	var hits []Hit
+	for i, d := range docs {
+		score := 0.0
+		for _, t := range terms {
+			f := float64(tfs[i][t])
+			if f == 0 {
+				continue
+			}
+			idf := math.Log(1 + (n-float64(df[t])+0.5)/(float64(df[t])+0.5))
+			norm := f + bm25K1*(1-bm25B+bm25B*float64(lengths[i])/avgLen)
+			score += idf * f * (bm25K1 + 1) / norm
+		}
+		if score > 0 {
+			hits = append(hits, Hit{ID: d.ID, Score: score})
+		}
+	}
+
+	// STEP 3: Best first; ties keep the candidate order (callers pass newest first)
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	return hits
+}
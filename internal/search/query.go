@@ -0,0 +1,88 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
+)
+
+// maxClauses bounds the size of the SQL full-text expression built from a query
+const maxClauses = 16
+
+// Clause is one part of a full-text query: a word, a quoted phrase, or either
+// ending in a prefix (data*, "machine learn*")
+// Words written with inner punctuation ("e-mail", "U.S.") are phrases of their parts,
+// as the full-text indexes split them that way
+type Clause struct {
+	Words  []string // Case-folded runs of letters and digits, in order
+	Prefix bool     // The last word matches any word it starts
+}
+
+// Phrase reports whether the clause is several words that must be adjacent
+func (c Clause) Phrase() bool { return len(c.Words) > 1 }
+
+// Query is a parsed full-text query; every clause must match
+type Query struct {
+	Clauses []Clause
+}
+
+// ParseQuery reads words, "quoted phrases" and trailing-* prefixes
+// Any other punctuation only separates words, so the result is safe to turn into
+// tsquery or FTS MATCH syntax; clauses beyond the first 16 are dropped
+func ParseQuery(q string) Query {
+	var query Query
+	add := func(text string, prefix bool) {
+		words := queryWords(text)
+		if len(words) > 0 && len(query.Clauses) < maxClauses {
+			query.Clauses = append(query.Clauses, Clause{Words: words, Prefix: prefix})
+		}
+	}
+	for q != "" {
+		q = strings.TrimLeftFunc(q, unicode.IsSpace)
+		if rest, ok := strings.CutPrefix(q, `"`); ok {
+			phrase, after, _ := strings.Cut(rest, `"`)
+			prefix := strings.HasSuffix(strings.TrimSpace(phrase), "*")
+			if next, ok := strings.CutPrefix(after, "*"); ok {
+				prefix, after = true, next
+			}
+			add(phrase, prefix)
+			q = after
+			continue
+		}
+		end := strings.IndexFunc(q, func(r rune) bool { return unicode.IsSpace(r) || r == '"' })
+		if end < 0 {
+			end = len(q)
+		}
+		add(q[:end], strings.HasSuffix(q[:end], "*"))
+		q = q[end:]
+	}
+	return query
+}
+
+// Terms returns every word of the query once, in order
+func (q Query) Terms() []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, c := range q.Clauses {
+		for _, w := range c.Words {
+			if !seen[w] {
+				seen[w] = true
+				terms = append(terms, w)
+			}
+		}
+	}
+	return terms
+}
+
+// queryWords splits text into case-folded runs of letters, marks and digits
+func queryWords(text string) []string {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && !unicode.IsMark(r)
+	})
+	words := make([]string, 0, len(fields))
+	for _, f := range fields {
+		words = append(words, analyzer.Fold(f))
+	}
+	return words
+}
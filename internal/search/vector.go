@@ -0,0 +1,79 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// VectorIndex is an in-memory exact (brute-force) cosine index
+// It backs semantic search on databases without native vector support (SQLite);
+// a linear scan is fast enough for the corpus sizes this service targets
+type VectorIndex struct {
+	mu      sync.RWMutex
+	vectors map[string][]float32
+}
+
+func NewVectorIndex() *VectorIndex {
+	return &VectorIndex{vectors: make(map[string][]float32)}
+}
+
+// Add stores or replaces the vector for id
+func (x *VectorIndex) Add(id string, vec []float32) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.vectors[id] = vec
+}
+
+// Remove drops id from the index
+func (x *VectorIndex) Remove(id string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	delete(x.vectors, id)
+}
+
+// Len returns the number of indexed vectors
+func (x *VectorIndex) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.vectors)
+}
+
+// Search returns the k most similar vectors to query by cosine similarity, best first
+func (x *VectorIndex) Search(query []float32, k int) []Hit {
+	x.mu.RLock()
+	hits := make([]Hit, 0, len(x.vectors))
+	for id, vec := range x.vectors {
+		if len(vec) != len(query) {
+			continue
+		}
+		hits = append(hits, Hit{ID: id, Score: Cosine(query, vec)})
+	}
+	x.mu.RUnlock()
+
+	// Sort by score, then ID, so equal scores come back in a stable order
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if k > 0 && len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits
+}
+
+// Cosine returns the cosine similarity of a and b (0 when either is all zeros)
+func Cosine(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,522 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+)
+
+const (
+	defaultAnalysesPage = 20
+	maxAnalysesPage     = 100
+	defaultAnalysesSort = "-created_at"
+)
+
+// AnalysesHandler lists stored analyses a page at a time (GET /analyses?limit=20&sort=-created_at)
+// sort is created_at, confidence or title, prefixed with "-" for descending order;
+// pass next_cursor back as ?cursor= with the same sort for the following page
+func (s *Server) AnalysesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	limit, err := parseLimit(r, defaultAnalysesPage, maxAnalysesPage)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	order, err := parseSort(r.URL.Query().Get("sort"), defaultAnalysesSort, sortColumns)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var conditions []string
+	var args []interface{}
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		cursor, err := decodeCursor(v, order)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var condition string
+		condition, args, err = order.after(s, cursor, args)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		conditions = append(conditions, condition)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	// One row more than the page tells whether there is a next page
+	args = append(args, limit+1)
+	rows, err := s.DB.QueryContext(r.Context(), `SELECT `+analysisColumns+` FROM analyses`+where+
+		` ORDER BY `+order.orderBy()+fmt.Sprintf(` LIMIT $%d`, len(args)), args...)
+	if err != nil {
+		http.Error(w, "db query failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	analyses, err := s.scanAnalyses(rows)
+	if err != nil {
+		http.Error(w, "row scan failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	page := models.AnalysisPage{Analyses: []models.Analysis{}}
+	if len(analyses) > limit {
+		analyses = analyses[:limit]
+		page.NextCursor = encodeCursor(order, models.SearchResult{Analysis: analyses[limit-1]})
+	}
+	page.Analyses = append(page.Analyses, analyses...)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(page)
+}
+
+// AnalysisHandler fetches (GET), edits (PATCH) or deletes (DELETE) one analysis at /analyses/{id}
+// Edits and deletes require "Authorization: Bearer <ADMIN_TOKEN>"; without an admin token
+// configured the analyses are read-only
+func (s *Server) AnalysisHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.getAnalysis(w, r)
+	case http.MethodPatch, http.MethodDelete:
+		if s.AdminToken == "" {
+			http.Error(w, "editing analyses is disabled", http.StatusForbidden)
+			return
+		}
+		if !s.authorizedAdmin(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodPatch {
+			s.patchAnalysis(w, r)
+		} else {
+			s.deleteAnalysis(w, r)
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) getAnalysis(w http.ResponseWriter, r *http.Request) {
+	analysis, ok, err := s.loadAnalysis(r.Context(), r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "db query failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "analysis not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(analysis)
+}
+
+// patchAnalysis applies human corrections to title, topics and sentiment
+// Edited fields are attributed to SourceHuman and updated_at is set; fields left out stay as they are
+func (s *Server) patchAnalysis(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Title     *string   `json:"title"`
+		Topics    *[]string `json:"topics"`
+		Sentiment *string   `json:"sentiment"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&input); err != nil {
+		http.Error(w, "invalid input: only title, topics and sentiment can be edited", http.StatusBadRequest)
+		return
+	}
+	if input.Title == nil && input.Topics == nil && input.Sentiment == nil {
+		http.Error(w, "invalid input: nothing to update", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	id := r.PathValue("id")
+	current, ok, err := s.loadAnalysis(ctx, id)
+	if err != nil {
+		http.Error(w, "db query failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "analysis not found", http.StatusNotFound)
+		return
+	}
+	sources := current.Sources
+	if sources == nil {
+		sources = make(map[string]string)
+	}
+
+	var sets []string
+	var args []interface{}
+	set := func(column string, value interface{}) {
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+		sources[column] = SourceHuman
+	}
+	if input.Title != nil {
+		title := strings.TrimSpace(*input.Title)
+		if title == "" {
+			http.Error(w, "invalid input: title is empty", http.StatusBadRequest)
+			return
+		}
+		set(FieldTitle, title)
+	}
+	var topics []string
+	if input.Topics != nil {
+		if topics, err = cleanTopics(*input.Topics); err != nil {
+			http.Error(w, "invalid input: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		set(FieldTopics, s.formatArrayForInsert(topics))
+	}
+	if input.Sentiment != nil {
+		sentiment := strings.ToLower(strings.TrimSpace(*input.Sentiment))
+		if !analyzer.IsSentimentLabel(sentiment) {
+			http.Error(w, "invalid input: sentiment must be positive, neutral or negative", http.StatusBadRequest)
+			return
+		}
+		set(FieldSentiment, sentiment)
+	}
+	args = append(args, jsonValue(sources), id)
+	query := `UPDATE analyses SET ` + strings.Join(sets, ", ") +
+		fmt.Sprintf(`, field_sources = $%d, updated_at = CURRENT_TIMESTAMP WHERE id = $%d`, len(args)-1, len(args))
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		http.Error(w, "failed to begin db transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		http.Error(w, "failed to update db: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if input.Topics != nil {
+		if err := replaceTopics(ctx, tx, id, topics); err != nil {
+			http.Error(w, "failed to update db: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "failed to commit db transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	updated, ok, err := s.loadAnalysis(ctx, id)
+	if err != nil || !ok {
+		http.Error(w, "db query failed: analysis changed during update", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(updated)
+}
+
+// cleanTopics trims topics and drops repeats that match as the same term
+func cleanTopics(topics []string) ([]string, error) {
+	seen := make(map[string]bool, len(topics))
+	out := make([]string, 0, len(topics))
+	for _, t := range topics {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			return nil, fmt.Errorf("topics must not be empty")
+		}
+		if key := termKey(t); !seen[key] {
+			seen[key] = true
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+// deleteAnalysis removes an analysis with everything derived from it in one transaction
+func (s *Server) deleteAnalysis(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := r.PathValue("id")
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		http.Error(w, "failed to begin db transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if err := s.deleteAnalysisTx(ctx, tx, id); err == sql.ErrNoRows {
+		http.Error(w, "analysis not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "failed to delete from db: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "failed to commit db transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if s.Vectors != nil {
+		s.Vectors.Remove(id)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// countedCondition holds for analyses counted in the corpus statistics: those the enrich
+// stage measured. Without it text_stats holds a JSON null, so word_count is what tells them apart
+const countedCondition = `word_count IS NOT NULL`
+
+// deleteAnalysisTx deletes an analysis, its mentions, terms, embedding, fingerprints, redaction
+// map and cluster membership, and takes it out of the corpus statistics
+// SQLite doesn't enforce ON DELETE CASCADE, so dependent rows are deleted explicitly
+// The oldest near copy linked to the analysis becomes the canonical one of the others;
+// entities and clusters left without members are dropped
+func (s *Server) deleteAnalysisTx(ctx context.Context, tx *sql.Tx, id string) error {
+	var text, lang string
+	var counted bool
+	err := tx.QueryRowContext(ctx,
+		`SELECT raw_text, COALESCE(language, ''), `+countedCondition+` FROM analyses WHERE id = $1`, id,
+	).Scan(&text, &lang, &counted)
+	if err != nil {
+		return err
+	}
+
+	var successor string
+	err = tx.QueryRowContext(ctx,
+		`SELECT id FROM analyses WHERE duplicate_of = $1 ORDER BY created_at, id LIMIT 1`, id).Scan(&successor)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("find linked copies: %w", err)
+	}
+	if successor != "" {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE analyses SET duplicate_of = $1 WHERE duplicate_of = $2 AND id <> $1`, successor, id); err != nil {
+			return fmt.Errorf("relink copies: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE analyses SET duplicate_of = NULL WHERE id = $1`, successor); err != nil {
+			return fmt.Errorf("relink copies: %w", err)
+		}
+	}
+
+	for _, table := range []string{"entity_mentions", "analysis_terms", "analysis_embeddings", "analysis_fingerprints", "redaction_maps", "cluster_members"} {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE analysis_id = $1`, id); err != nil {
+			return fmt.Errorf("delete from %s: %w", table, err)
+		}
+	}
+	for _, stmt := range []string{
+		`DELETE FROM entities WHERE NOT EXISTS (SELECT 1 FROM entity_mentions m WHERE m.entity_id = entities.id)`,
+		`DELETE FROM clusters WHERE NOT EXISTS (SELECT 1 FROM cluster_members m WHERE m.cluster_id = clusters.id)`,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	// Analyses stored without the enrich stage (no text statistics) were never counted
+	if counted {
+		if err := s.removeCorpusStats(ctx, tx, text, lang); err != nil {
+			return fmt.Errorf("update corpus stats: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM analyses WHERE id = $1`, id)
+	return err
+}
+
+// removeCorpusStats undoes addCorpusStats for a deleted text, recounting its terms
+// with the current keyword options; POST /keywords/recompute rebuilds exact statistics
+func (s *Server) removeCorpusStats(ctx context.Context, tx *sql.Tx, text, lang string) error {
+	opts := s.KeywordOptions
+	opts.Language = lang
+	doc := analyzer.CountTerms(text, opts)
+	if err := addDocumentCounts(ctx, tx, doc.Language, -1, -doc.Length); err != nil {
+		return err
+	}
+	if err := addTermFrequencies(ctx, tx, doc.Language, doc.Keys(), -1); err != nil {
+		return err
+	}
+	for _, stmt := range []string{
+		`DELETE FROM term_stats WHERE documents <= 0`,
+		`DELETE FROM corpus_stats WHERE documents <= 0`,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadAnalysis fetches one analysis with its entities; ok is false when it doesn't exist
+func (s *Server) loadAnalysis(ctx context.Context, id string) (analysis models.Analysis, ok bool, err error) {
+	byID, err := s.loadAnalyses(ctx, []string{id})
+	if err != nil {
+		return analysis, false, err
+	}
+	if analysis, ok = byID[id]; !ok {
+		return analysis, false, nil
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT e.id, e.type, e.name, m.mention, m.start_offset, m.end_offset
+		FROM entity_mentions m
+		JOIN entities e ON e.id = m.entity_id
+		WHERE m.analysis_id = $1
+		ORDER BY m.start_offset`, id)
+	if err != nil {
+		return analysis, false, err
+	}
+	defer rows.Close()
+	index := make(map[string]int)
+	for rows.Next() {
+		var e models.Entity
+		var m models.Mention
+		if err := rows.Scan(&e.ID, &e.Type, &e.Name, &m.Text, &m.Start, &m.End); err != nil {
+			return analysis, false, err
+		}
+		i, seen := index[e.ID]
+		if !seen {
+			i = len(analysis.Entities)
+			index[e.ID] = i
+			analysis.Entities = append(analysis.Entities, e)
+		}
+		analysis.Entities[i].Mentions = append(analysis.Entities[i].Mentions, m)
+	}
+	return analysis, true, rows.Err()
+}
+
+// sortColumn is a column analyses can be listed by
+type sortColumn struct {
+	expr    string                                           // SQL expression ordered on
+	key     func(r models.SearchResult) string               // Cursor value of a row
+	param   func(s *Server, key string) (interface{}, error) // Query parameter for a cursor value
+	reverse bool                                             // The plain name sorts descending (best first)
+}
+
+// sortColumns are the values of ?sort=; rows with equal values are ordered by id
+var sortColumns = map[string]sortColumn{
+	"created_at": {
+		expr: "created_at",
+		key:  func(r models.SearchResult) string { return r.CreatedAt.UTC().Format(time.RFC3339Nano) },
+		param: func(s *Server, key string) (interface{}, error) {
+			t, err := time.Parse(time.RFC3339Nano, key)
+			if err != nil {
+				return nil, err
+			}
+			return s.timeParam(t), nil
+		},
+	},
+	"confidence": {
+		expr:  "COALESCE(confidence, 0)",
+		key:   func(r models.SearchResult) string { return strconv.FormatFloat(r.Confidence, 'g', -1, 64) },
+		param: floatParam,
+	},
+	"title": {
+		expr:  "COALESCE(title, '')",
+		key:   func(r models.SearchResult) string { return r.Title },
+		param: func(s *Server, key string) (interface{}, error) { return key, nil },
+	},
+}
+
+// floatParam reads the cursor value of a numeric column
+func floatParam(s *Server, key string) (interface{}, error) {
+	return strconv.ParseFloat(key, 64)
+}
+
+// sortOrder is a parsed ?sort= value
+type sortOrder struct {
+	name   string
+	column sortColumn
+	desc   bool
+}
+
+// parseSort reads a sort name from columns, "-" prefixed for the opposite order
+// (descending for most columns); empty selects def
+func parseSort(v, def string, columns map[string]sortColumn) (sortOrder, error) {
+	if v == "" {
+		v = def
+	}
+	name, reversed := strings.CutPrefix(v, "-")
+	column, ok := columns[name]
+	if !ok {
+		names := make([]string, 0, len(columns))
+		for n := range columns {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return sortOrder{}, fmt.Errorf("invalid sort (%s, - prefix to reverse)", strings.Join(names, ", "))
+	}
+	return sortOrder{name: name, column: column, desc: reversed != column.reverse}, nil
+}
+
+// String is the ?sort= value selecting the order
+func (o sortOrder) String() string {
+	if o.desc != o.column.reverse {
+		return "-" + o.name
+	}
+	return o.name
+}
+
+func (o sortOrder) direction() string {
+	if o.desc {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+// orderBy is the ORDER BY clause of the order, id breaking ties
+func (o sortOrder) orderBy() string {
+	return fmt.Sprintf("%[1]s %[2]s, id %[2]s", o.column.expr, o.direction())
+}
+
+// after returns the condition selecting rows that come after cursor in this order,
+// with its parameters appended to args
+func (o sortOrder) after(s *Server, c pageCursor, args []interface{}) (string, []interface{}, error) {
+	value, err := o.column.param(s, c.Value)
+	if err != nil {
+		return "", args, fmt.Errorf("invalid cursor")
+	}
+	op := ">"
+	if o.desc {
+		op = "<"
+	}
+	args = append(args, value, c.ID)
+	v, id := len(args)-1, len(args)
+	return fmt.Sprintf("(%[1]s %[2]s $%[3]d OR (%[1]s = $%[3]d AND id %[2]s $%[4]d))", o.column.expr, op, v, id), args, nil
+}
+
+// pageCursor is the position after the last row of a page: its sort value and id
+type pageCursor struct {
+	Sort  string `json:"s"` // The ?sort= value, direction included
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// encodeCursor returns the opaque cursor of the page ending at r
+func encodeCursor(o sortOrder, r models.SearchResult) string {
+	b, _ := json.Marshal(pageCursor{Sort: o.String(), Value: o.column.key(r), ID: r.ID})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor reads a cursor, which must come from a page in the same sort column and direction
+func decodeCursor(v string, o sortOrder) (pageCursor, error) {
+	var c pageCursor
+	b, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil || json.Unmarshal(b, &c) != nil || c.ID == "" {
+		return c, fmt.Errorf("invalid cursor")
+	}
+	if c.Sort != o.String() {
+		return c, fmt.Errorf("cursor was issued for sort %s", c.Sort)
+	}
+	return c, nil
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
+	"github.com/gbengafagbola/knowledge-extractor/internal/llm"
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+	"github.com/gbengafagbola/knowledge-extractor/internal/search"
+)
+
+const (
+	defaultAskSources = 5   // Analyses handed to the LLM as context
+	maxAskSources     = 20  // Upper bound for the "limit" request field
+	askCandidates     = 200 // Rows pre-filtered in SQL before BM25 ranking
+	extractiveQuotes  = 3   // Sentences quoted by the extractive fallback
+)
+
+// AskHandler answers a question from the stored analyses (retrieval-augmented)
+// Algorithm: keyword pre-filter in SQL -> BM25 ranking -> LLM answer with citations,
+// or an extractive answer quoting the best sentences when no LLM can answer
+func (s *Server) AskHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var input struct {
+		Question string `json:"question"`
+		Limit    int    `json:"limit"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || strings.TrimSpace(input.Question) == "" {
+		http.Error(w, "invalid input", http.StatusBadRequest)
+		return
+	}
+	if input.Limit <= 0 {
+		input.Limit = defaultAskSources
+	}
+	if input.Limit > maxAskSources {
+		input.Limit = maxAskSources
+	}
+
+	terms, lang := search.QueryTerms(input.Question)
+	if len(terms) == 0 {
+		http.Error(w, "question has no searchable terms", http.StatusBadRequest)
+		return
+	}
+
+	// RETRIEVAL: cheap SQL pre-filter, then rank candidates with BM25
+	candidates, err := s.loadCandidates(r.Context(), terms, askCandidates)
+	if err != nil {
+		http.Error(w, "db query failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	byID := make(map[string]models.Analysis, len(candidates))
+	docs := make([]search.Document, 0, len(candidates))
+	for _, a := range candidates {
+		byID[a.ID] = a
+		docs = append(docs, search.Document{ID: a.ID, Text: a.Title + "\n" + a.Summary + "\n" + a.RawText})
+	}
+	hits := search.RankBM25(terms, lang, docs)
+	if len(hits) > input.Limit {
+		hits = hits[:input.Limit]
+	}
+
+	answer := models.Answer{Question: input.Question, Sources: []models.AnswerSource{}}
+	sources := make([]llm.Source, 0, len(hits))
+	for _, h := range hits {
+		a := byID[h.ID]
+		answer.Sources = append(answer.Sources, models.AnswerSource{AnalysisID: a.ID, Title: a.Title, Score: h.Score})
+		sources = append(sources, llm.Source{ID: a.ID, Title: a.Title, Text: a.RawText})
+	}
+
+	// GENERATION: LLM answer when supported, extractive fallback otherwise
+	if text, citations, ok := s.llmAnswer(input.Question, sources); ok {
+		answer.Answer, answer.Citations, answer.Mode = text, citations, "llm"
+	} else {
+		extractDocs := make([]search.Document, 0, len(sources))
+		for _, src := range sources {
+			extractDocs = append(extractDocs, search.Document{ID: src.ID, Text: src.Text})
+		}
+		answer.Answer, answer.Citations = search.ExtractiveAnswer(terms, lang, extractDocs, extractiveQuotes)
+		answer.Mode = "extractive"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(answer)
+}
+
+// llmAnswer asks the LLM for an answer when it supports QuestionAnswerer
+// Citations are kept only if their quote is found in the cited source's raw text
+func (s *Server) llmAnswer(question string, sources []llm.Source) (string, []models.Citation, bool) {
+	qa, ok := s.LLM.(llm.QuestionAnswerer)
+	if !ok || len(sources) == 0 {
+		return "", nil, false
+	}
+	text, citations, err := qa.Answer(question, sources)
+	if err != nil || strings.TrimSpace(text) == "" {
+		if err != nil {
+			fmt.Println("LLM answer failed, using extractive answer:", err)
+		}
+		return "", nil, false
+	}
+
+	sourceText := make(map[string]string, len(sources))
+	for _, src := range sources {
+		sourceText[src.ID] = src.Text
+	}
+	verified := []models.Citation{}
+	for _, c := range citations {
+		raw, known := sourceText[c.AnalysisID]
+		if !known {
+			continue
+		}
+		if start, end, found := analyzer.LocateQuote(raw, c.Quote); found {
+			c.Start, c.End = start, end
+			verified = append(verified, c)
+		}
+	}
+	return text, verified, true
+}
+
+// loadCandidates returns recent analyses whose title, summary or raw text
+// contains any of the terms (case-insensitive), newest first
+// Terms are matched literally: tokens may contain "_", a LIKE wildcard
+func (s *Server) loadCandidates(ctx context.Context, terms []string, limit int) ([]models.Analysis, error) {
+	clauses := make([]string, 0, len(terms))
+	args := make([]interface{}, 0, len(terms)+1)
+	for i, t := range terms {
+		p := fmt.Sprintf("$%d", i+1)
+		clauses = append(clauses, fmt.Sprintf(
+			`lower(raw_text) LIKE %[1]s ESCAPE '\' OR lower(summary) LIKE %[1]s ESCAPE '\' OR lower(title) LIKE %[1]s ESCAPE '\'`, p))
+		args = append(args, "%"+likeEscaper.Replace(t)+"%")
+	}
+	args = append(args, limit)
+
+	query := fmt.Sprintf(`SELECT %s FROM analyses WHERE %s ORDER BY created_at DESC LIMIT $%d`,
+		analysisColumns, strings.Join(clauses, " OR "), len(args))
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return s.scanAnalyses(rows)
+}
+
+// likeEscaper escapes LIKE wildcards for an ESCAPE '\' pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
@@ -0,0 +1,262 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
+	"github.com/gbengafagbola/knowledge-extractor/internal/cluster"
+	"github.com/gbengafagbola/knowledge-extractor/internal/llm"
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	maxClusters           = 50
+	clusterLabelTerms     = 3 // Terms joined into a label when the LLM can't name the cluster
+	clusterSampleTitles   = 5 // Most central member titles shown to the LLM
+	clusterSeed           = 1 // Fixed so reruns over the same corpus give the same clusters
+	defaultClusterMembers = 50
+	maxClusterMembers     = 500
+)
+
+// Label sources recorded per cluster
+const (
+	LabelFromLLM   = "llm"
+	LabelFromTerms = "terms"
+)
+
+// ClustersHandler lists the stored clusters, largest first (GET /clusters), or
+// reclusters every stored analysis (POST /clusters?k=8)
+// Reclustering replaces all clusters and requires "Authorization: Bearer <ADMIN_TOKEN>"
+// when an admin token is configured; without k the count grows with the corpus
+func (s *Server) ClustersHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		clusters, err := s.loadClusters(r.Context())
+		if err != nil {
+			http.Error(w, "db query failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(clusters)
+	case http.MethodPost:
+		if s.AdminToken != "" && !s.authorizedAdmin(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		k := 0
+		if v := r.URL.Query().Get("k"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 || n > maxClusters {
+				http.Error(w, fmt.Sprintf("invalid k (1-%d)", maxClusters), http.StatusBadRequest)
+				return
+			}
+			k = n
+		}
+		clusters, err := s.recluster(r.Context(), k)
+		if err != nil {
+			http.Error(w, "clustering failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(clusters)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ClusterHandler returns one cluster with its member analyses, most central first
+// (GET /clusters/{id}?limit=50)
+func (s *Server) ClusterHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	limit, err := parseLimit(r, defaultClusterMembers, maxClusterMembers)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	id := r.PathValue("id")
+	rows, err := s.DB.QueryContext(ctx, clusterQuery+` WHERE c.id = $1`, id)
+	if err != nil {
+		http.Error(w, "db query failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	clusters, err := scanClusters(rows)
+	if err != nil {
+		http.Error(w, "row scan failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(clusters) == 0 {
+		http.Error(w, "cluster not found", http.StatusNotFound)
+		return
+	}
+	c := clusters[0]
+
+	rows, err = s.DB.QueryContext(ctx, `
+		SELECT m.analysis_id, COALESCE(a.title, ''), COALESCE(a.summary, ''), m.similarity, a.created_at
+		FROM cluster_members m
+		JOIN analyses a ON a.id = m.analysis_id
+		WHERE m.cluster_id = $1
+		ORDER BY m.similarity DESC, a.created_at
+		LIMIT $2`, id, limit)
+	if err != nil {
+		http.Error(w, "db query failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	c.Members = []models.ClusterMember{}
+	for rows.Next() {
+		var m models.ClusterMember
+		if err := rows.Scan(&m.AnalysisID, &m.Title, &m.Summary, &m.Similarity, &m.CreatedAt); err != nil {
+			http.Error(w, "row scan failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		c.Members = append(c.Members, m)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "row scan failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(c)
+}
+
+// clusterQuery selects clusters with their current member counts, in scanClusters order
+const clusterQuery = `
+	SELECT c.id, c.label, c.label_source, c.terms, c.created_at,
+		(SELECT COUNT(*) FROM cluster_members m WHERE m.cluster_id = c.id)
+	FROM clusters c`
+
+// loadClusters returns every stored cluster, largest first
+func (s *Server) loadClusters(ctx context.Context) ([]models.Cluster, error) {
+	rows, err := s.DB.QueryContext(ctx, clusterQuery+` ORDER BY 6 DESC, c.label`)
+	if err != nil {
+		return nil, err
+	}
+	return scanClusters(rows)
+}
+
+func scanClusters(rows *sql.Rows) ([]models.Cluster, error) {
+	defer rows.Close()
+	clusters := []models.Cluster{}
+	for rows.Next() {
+		var c models.Cluster
+		if err := rows.Scan(&c.ID, &c.Label, &c.LabelSource, jsonColumn{&c.Terms}, &c.CreatedAt, &c.Size); err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, c)
+	}
+	return clusters, rows.Err()
+}
+
+// recluster runs k-means over every stored analysis, labels the clusters and
+// replaces the stored ones in a single transaction
+func (s *Server) recluster(ctx context.Context, k int) ([]models.Cluster, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT id, raw_text, COALESCE(language, ''), COALESCE(title, '') FROM analyses`)
+	if err != nil {
+		return nil, err
+	}
+	var docs []cluster.Document
+	titles := make(map[string]string)
+	for rows.Next() {
+		var id, text, lang, title string
+		if err := rows.Scan(&id, &text, &lang, &title); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		opts := s.KeywordOptions
+		opts.Language = lang
+		docs = append(docs, cluster.Document{ID: id, Terms: analyzer.CountTerms(text, opts)})
+		titles[id] = title
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	found := cluster.KMeans(docs, cluster.Options{K: k, Seed: clusterSeed})
+	now := time.Now().UTC()
+	clusters := make([]models.Cluster, len(found))
+	for i, c := range found {
+		var sample []string
+		for _, m := range c.Members {
+			if len(sample) == clusterSampleTitles {
+				break
+			}
+			if titles[m.ID] != "" {
+				sample = append(sample, titles[m.ID])
+			}
+		}
+		label, source := s.labelCluster(c.Terms, sample)
+		clusters[i] = models.Cluster{
+			ID:          uuid.NewString(),
+			Label:       label,
+			LabelSource: source,
+			Terms:       c.Terms,
+			Size:        len(c.Members),
+			CreatedAt:   now,
+		}
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	for _, stmt := range []string{`DELETE FROM cluster_members`, `DELETE FROM clusters`} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return nil, err
+		}
+	}
+	for i, c := range clusters {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO clusters (id, label, label_source, terms, created_at) VALUES ($1, $2, $3, $4, $5)`,
+			c.ID, c.Label, c.LabelSource, jsonValue(c.Terms), c.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range found[i].Members {
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO cluster_members (cluster_id, analysis_id, similarity) VALUES ($1, $2, $3)`,
+				c.ID, m.ID, m.Similarity)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return clusters, nil
+}
+
+// labelCluster names a cluster with the LLM when it supports ClusterLabeler,
+// otherwise with its top terms
+func (s *Server) labelCluster(terms, titles []string) (string, string) {
+	if labeler, ok := s.LLM.(llm.ClusterLabeler); ok && len(terms) > 0 {
+		label, err := labeler.LabelCluster(terms, titles)
+		if err == nil && label != "" {
+			return label, LabelFromLLM
+		}
+		if err != nil {
+			fmt.Println("LLM cluster labelling failed, using terms:", err)
+		}
+	}
+	if len(terms) > clusterLabelTerms {
+		terms = terms[:clusterLabelTerms]
+	}
+	return strings.Join(terms, ", "), LabelFromTerms
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+)
+
+// DuplicatePolicy decides what POST /analyze does with a near copy of a stored analysis
+type DuplicatePolicy string
+
+const (
+	DuplicateStore  DuplicatePolicy = "store"  // Analyze and store it, with duplicate_of pointing at the canonical analysis
+	DuplicateLink   DuplicatePolicy = "link"   // Skip analysis and return the canonical analysis
+	DuplicateReject DuplicatePolicy = "reject" // Refuse it with 409 Conflict
+)
+
+// ParseDuplicatePolicy reads a policy name; empty selects DuplicateStore
+func ParseDuplicatePolicy(name string) (DuplicatePolicy, bool) {
+	switch p := DuplicatePolicy(strings.ToLower(strings.TrimSpace(name))); p {
+	case "":
+		return DuplicateStore, true
+	case DuplicateStore, DuplicateLink, DuplicateReject:
+		return p, true
+	}
+	return "", false
+}
+
+// findCanonical returns the canonical analysis of the most similar stored near copy of sig,
+// or "" when none reaches DuplicateThreshold
+func (s *Server) findCanonical(ctx context.Context, sig analyzer.Signature) (string, error) {
+	candidates, err := s.duplicateCandidates(ctx, sig, "", "")
+	if err != nil {
+		return "", err
+	}
+	if len(candidates) == 0 || candidates[0].Similarity < s.DuplicateThreshold {
+		return "", nil
+	}
+	// Link to the original, not to an earlier copy of it
+	if best := candidates[0]; best.DuplicateOf != "" {
+		return best.DuplicateOf, nil
+	}
+	return candidates[0].AnalysisID, nil
+}
+
+// duplicateCandidates returns analyses other than exclude that share an LSH band with sig,
+// plus those in group (the canonical analysis and its linked copies) when set, most similar
+// first; callers decide which similarities count as duplicates
+func (s *Server) duplicateCandidates(ctx context.Context, sig analyzer.Signature, exclude, group string) ([]models.Duplicate, error) {
+	args := []interface{}{exclude}
+	clauses := make([]string, 0, analyzer.MinHashBands)
+	for band, value := range sig.Bands() {
+		args = append(args, band, value)
+		clauses = append(clauses, fmt.Sprintf("(band = $%d AND value = $%d)", len(args)-1, len(args)))
+	}
+	match := `id IN (SELECT analysis_id FROM analysis_fingerprints WHERE ` + strings.Join(clauses, " OR ") + `)`
+	if group != "" {
+		args = append(args, group)
+		match += fmt.Sprintf(` OR id = $%[1]d OR duplicate_of = $%[1]d`, len(args))
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT id, COALESCE(title, ''), minhash, COALESCE(duplicate_of, ''), created_at
+		FROM analyses
+		WHERE id <> $1 AND (`+match+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.Duplicate
+	for rows.Next() {
+		var d models.Duplicate
+		var stored []byte
+		if err := rows.Scan(&d.AnalysisID, &d.Title, &stored, &d.DuplicateOf, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		if other, ok := analyzer.SignatureFromBytes(stored); ok {
+			d.Similarity = sig.Similarity(other)
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Ties: the oldest copy is the most likely original
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Similarity != out[j].Similarity {
+			return out[i].Similarity > out[j].Similarity
+		}
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out, nil
+}
+
+// storeFingerprint indexes an analysis' signature by LSH band
+func storeFingerprint(ctx context.Context, tx *sql.Tx, analysisID string, sig analyzer.Signature) error {
+	args := []interface{}{analysisID}
+	values := make([]string, 0, analyzer.MinHashBands)
+	for band, value := range sig.Bands() {
+		args = append(args, band, value)
+		values = append(values, fmt.Sprintf("($1, $%d, $%d)", len(args)-1, len(args)))
+	}
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO analysis_fingerprints (analysis_id, band, value) VALUES `+strings.Join(values, ", "), args...)
+	return err
+}
+
+// DuplicatesHandler lists the near copies of an analysis (GET /analyses/{id}/duplicates):
+// stored analyses at least DuplicateThreshold similar to it, plus every analysis linked
+// to the same canonical one, most similar first
+func (s *Server) DuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := r.PathValue("id")
+
+	var stored []byte
+	var canonical string
+	err := s.DB.QueryRowContext(r.Context(),
+		`SELECT minhash, COALESCE(duplicate_of, '') FROM analyses WHERE id = $1`, id).Scan(&stored, &canonical)
+	if err == sql.ErrNoRows {
+		http.Error(w, "analysis not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "db query failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if canonical == "" {
+		canonical = id
+	}
+
+	sig, _ := analyzer.SignatureFromBytes(stored) // Unsigned rows only find their linked copies
+	candidates, err := s.duplicateCandidates(r.Context(), sig, id, canonical)
+	if err != nil {
+		http.Error(w, "db query failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	duplicates := []models.Duplicate{}
+	for _, d := range candidates {
+		if d.Similarity >= s.DuplicateThreshold || d.AnalysisID == canonical || d.DuplicateOf == canonical {
+			duplicates = append(duplicates, d)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(duplicates)
+}
+
+// nullString stores an empty string as NULL
+func nullString(v string) interface{} {
+	if v == "" {
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
+	"github.com/gbengafagbola/knowledge-extractor/internal/llm"
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+)
+
+// EntitiesHandler lists known entities with document and mention counts
+// Optional filters: type (person/organization/...), q (name prefix), limit
+func (s *Server) EntitiesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	entityType := r.URL.Query().Get("type")
+	if entityType != "" {
+		entityType = models.NormalizeEntityType(entityType)
+		if entityType == "" {
+			http.Error(w, "unknown entity type", http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit, err := parseLimit(r, 100, 1000)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := s.DB.QueryContext(r.Context(), `
+		SELECT e.id, e.type, e.name, COUNT(DISTINCT m.analysis_id), COUNT(m.analysis_id)
+		FROM entities e
+		JOIN entity_mentions m ON m.entity_id = e.id
+		WHERE ($1 = '' OR e.type = $1)
+		  AND ($2 = '' OR lower(e.name) LIKE lower($2) || '%')
+		GROUP BY e.id, e.type, e.name
+		ORDER BY COUNT(DISTINCT m.analysis_id) DESC, e.name
+		LIMIT $3`,
+		entityType, r.URL.Query().Get("q"), limit,
+	)
+	if err != nil {
+		http.Error(w, "db query failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	results := []models.EntityStat{}
+	for rows.Next() {
+		var e models.EntityStat
+		if err := rows.Scan(&e.ID, &e.Type, &e.Name, &e.Documents, &e.Mentions); err != nil {
+			http.Error(w, "row scan failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		results = append(results, e)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "db query failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(results)
+}
+
+// extractEntities prefers the LLM when it supports entity extraction and
+// falls back to the local rule-based extractor on error or empty output;
+// source reports which one produced the entities
+func (s *Server) extractEntities(text string) (entities []models.Entity, source string) {
+	if extractor, ok := s.LLM.(llm.EntityExtractor); ok {
+		entities, err := extractor.ExtractEntities(text)
+		if err == nil {
+			if located := analyzer.LocateMentions(text, entities); len(located) > 0 {
+				return located, SourceLLM
+			}
+		} else {
+			fmt.Println("LLM entity extraction failed, using local rules:", err)
+		}
+	}
+	return analyzer.ExtractEntities(text), SourceLocal
+}
+
+// storeEntities upserts each entity and records its mentions for the analysis
+func (s *Server) storeEntities(ctx context.Context, tx *sql.Tx, analysisID string, entities []models.Entity) error {
+	for _, e := range entities {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO entities (id, type, name) VALUES ($1,$2,$3) ON CONFLICT (id) DO NOTHING`,
+			e.ID, e.Type, e.Name,
+		)
+		if err != nil {
+			return fmt.Errorf("insert entity: %w", err)
+		}
+
+		for _, m := range e.Mentions {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO entity_mentions (analysis_id, entity_id, mention, start_offset, end_offset)
+				VALUES ($1,$2,$3,$4,$5)`,
+				analysisID, e.ID, m.Text, m.Start, m.End,
+			)
+			if err != nil {
+				return fmt.Errorf("insert entity mention: %w", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+	"github.com/gbengafagbola/knowledge-extractor/internal/search"
+)
+
+// keywordSearch retrieves analyses containing any of terms from the full-text
+// index, best first. Scores are ts_rank_cd on Postgres and BM25 on SQLite
+// (FTS5's bm25(), or BM25 computed locally over FTS4 matches)
+func (s *Server) keywordSearch(ctx context.Context, terms []string, lang string, limit int) ([]search.Hit, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	if s.Driver == "postgres" {
+		lexemes := make([]string, len(terms))
+		for i, t := range terms {
+			lexemes[i] = tsLexeme(t)
+		}
+		rows, err := s.DB.QueryContext(ctx, `
+			SELECT id, ts_rank_cd(search_vector, query)
+			FROM analyses, to_tsquery('simple', $1) query
+			WHERE search_vector @@ query
+			ORDER BY 2 DESC, created_at DESC
+			LIMIT $2`,
+			strings.Join(lexemes, " | "), limit,
+		)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		return scanHits(rows)
+	}
+
+	module, err := s.sqliteFTSModule()
+	if err != nil {
+		return nil, err
+	}
+	match := ftsOrQuery(terms)
+
+	if module == "fts5" {
+		// bm25() is lower-is-better, so negate it for a higher-is-better score
+		rows, err := s.DB.QueryContext(ctx, `
+			SELECT id, -bm25(analyses_fts)
+			FROM analyses_fts
+			WHERE analyses_fts MATCH $1
+			ORDER BY bm25(analyses_fts)
+			LIMIT $2`,
+			match, limit,
+		)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		return scanHits(rows)
+	}
+
+	// FTS4 has no ranking function: fetch matches and rank them with BM25 locally
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT id, coalesce(title, '') || ' ' || coalesce(summary, '') || ' ' || coalesce(raw_text, '')
+		FROM analyses_fts
+		WHERE analyses_fts MATCH $1
+		LIMIT $2`,
+		match, askCandidates,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var docs []search.Document
+	for rows.Next() {
+		var d search.Document
+		if err := rows.Scan(&d.ID, &d.Text); err != nil {
+			return nil, err
+		}
+		docs = append(docs, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	hits := search.RankBM25(terms, lang, docs)
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// tsLexeme quotes a term as a tsquery lexeme; tokens may contain apostrophes,
+// colons and other punctuation that tsquery would read as operators
+func tsLexeme(term string) string {
+	return "'" + tsLexemeEscaper.Replace(term) + "'"
+}
+
+var tsLexemeEscaper = strings.NewReplacer(`'`, `''`, `\`, `\\`)
+
+// ftsOrQuery builds an FTS MATCH expression matching any of the terms
+// Each term is a quoted phrase; a '"' inside a token (as in Hebrew acronyms) becomes a word break
+func ftsOrQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, t := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(t, `"`, " ") + `"`
+	}
+	return strings.Join(quoted, " OR ")
+}
+
+// scanHits reads (id, score) rows
+func scanHits(rows *sql.Rows) ([]search.Hit, error) {
+	var hits []search.Hit
+	for rows.Next() {
+		var h search.Hit
+		if err := rows.Scan(&h.ID, &h.Score); err != nil {
+			return nil, err
+		}
+		hits = append(hits, h)
+	}
+	return hits, rows.Err()
+}
+
+// Highlight markers around matched words in search snippets
+const (
+	snippetStart = "<mark>"
+	snippetEnd   = "</mark>"
+)
+
+// matchExpression turns a parsed query into the index's query syntax
+func (s *Server) matchExpression(query search.Query, module string) string {
+	if s.Driver == "postgres" {
+		return tsQuery(query)
+	}
+	return ftsQuery(query, module)
+}
+
+// hitsQuery selects the analyses matching the expression in $1 as (hit_id, score[, snippet]),
+// with a highlighted snippet of raw_text when snippet is set
+// Titles weigh more than summaries and summaries more than the text; FTS4 has no ranking
+// function, so its scores are 0 until scoreResults
+func (s *Server) hitsQuery(module string, snippet bool) string {
+	if s.Driver == "postgres" {
+		hits := `SELECT a.id AS hit_id, ts_rank_cd(a.search_vector, q)::float8 AS score`
+		if snippet {
+			hits += `, ts_headline('simple', a.raw_text, q,
+				'StartSel="` + snippetStart + `", StopSel="` + snippetEnd + `", MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=" … "') AS snippet`
+		}
+		return hits + ` FROM analyses a, to_tsquery('simple', $1) q WHERE a.search_vector @@ q`
+	}
+	// Columns: id, title, summary, raw_text; snippets come from raw_text (column 3)
+	hits := `SELECT id AS hit_id, -bm25(analyses_fts, 0, 3, 2, 1) AS score`
+	if snippet {
+		hits += `, snippet(analyses_fts, 3, '` + snippetStart + `', '` + snippetEnd + `', '…', 24) AS snippet`
+	}
+	if module != "fts5" {
+		hits = `SELECT id AS hit_id, 0.0 AS score`
+		if snippet {
+			hits += `, snippet(analyses_fts, '` + snippetStart + `', '` + snippetEnd + `', '…', 3, 24) AS snippet`
+		}
+	}
+	return hits + ` FROM analyses_fts WHERE analyses_fts MATCH $1`
+}
+
+// scanSearchResults reads rows of analysisColumns followed by a score and a snippet
+func (s *Server) scanSearchResults(rows *sql.Rows) ([]models.SearchResult, error) {
+	var results []models.SearchResult
+	for rows.Next() {
+		var r models.SearchResult
+		dest, finish := s.analysisDest(&r.Analysis)
+		if err := rows.Scan(append(dest, &r.Score, &r.Snippet)...); err != nil {
+			return nil, err
+		}
+		finish()
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// scoreResults sets the BM25 scores of FTS4 matches over title, summary (both repeated
+// for weight) and text; matches only a prefix reaches keep a score of 0
+func scoreResults(query search.Query, results []models.SearchResult) {
+	docs := make([]search.Document, len(results))
+	index := make(map[string]int, len(results))
+	for i, r := range results {
+		a := r.Analysis
+		text := strings.Join([]string{a.Title, a.Title, a.Title, a.Summary, a.Summary, a.RawText}, "\n")
+		docs[i] = search.Document{ID: a.ID, Text: text}
+		index[a.ID] = i
+	}
+	for _, h := range search.RankBM25(query.Terms(), analyzer.LanguageUndetermined, docs) {
+		results[index[h.ID]].Score = h.Score
+	}
+}
+
+// tsQuery turns a parsed query into tsquery syntax: words joined with &,
+// phrases with <->, and :* after prefixes
+func tsQuery(query search.Query) string {
+	clauses := make([]string, len(query.Clauses))
+	for i, c := range query.Clauses {
+		clause := strings.Join(c.Words, " <-> ")
+		if c.Prefix {
+			clause += ":*"
+		}
+		if c.Phrase() {
+			clause = "(" + clause + ")"
+		}
+		clauses[i] = clause
+	}
+	return strings.Join(clauses, " & ")
+}
+
+// ftsQuery turns a parsed query into an FTS MATCH expression of quoted clauses,
+// implicitly ANDed; FTS5 puts the prefix * after the closing quote, FTS4 inside it
+func ftsQuery(query search.Query, module string) string {
+	clauses := make([]string, len(query.Clauses))
+	for i, c := range query.Clauses {
+		clause := strings.Join(c.Words, " ")
+		switch {
+		case c.Prefix && module == "fts5":
+			clause = `"` + clause + `"*`
+		case c.Prefix:
+			clause = `"` + clause + `*"`
+		default:
+			clause = `"` + clause + `"`
+		}
+		clauses[i] = clause
+	}
+	return strings.Join(clauses, " ")
+}
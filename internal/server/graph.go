@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/graph"
+	"github.com/lib/pq"
+)
+
+const (
+	defaultGraphNodes = 50
+	maxGraphNodes     = 500
+	maxGraphDepth     = 3
+)
+
+// GraphHandler returns the concept co-occurrence graph (GET /graph)
+// Params: center=term (neighbourhood of that concept; the busiest concepts otherwise),
+// depth=1..3 (default 1), nodes=topics,keywords,entities (default all),
+// min_count=shared documents for an edge (default 1), limit=max nodes,
+// format=json|graphml|gexf|dot (default json)
+func (s *Server) GraphHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+
+	format := q.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	contentType, ok := graph.ContentTypes[format]
+	if !ok {
+		http.Error(w, "invalid format (json, graphml, gexf or dot)", http.StatusBadRequest)
+		return
+	}
+	depth, err := parseIntParam(r, "depth", 1, 1, maxGraphDepth)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	minCount, err := parseIntParam(r, "min_count", 1, 1, 1<<30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, err := parseLimit(r, defaultGraphNodes, maxGraphNodes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	kinds := map[string]bool{"topics": true, "keywords": true, "entities": true}
+	if v := q.Get("nodes"); v != "" {
+		kinds = make(map[string]bool)
+		for _, k := range strings.Split(v, ",") {
+			k = strings.TrimSpace(k)
+			if k != "topics" && k != "keywords" && k != "entities" {
+				http.Error(w, "invalid nodes (topics, keywords and/or entities)", http.StatusBadRequest)
+				return
+			}
+			kinds[k] = true
+		}
+	}
+
+	builder, err := s.buildGraph(r.Context(), kinds)
+	if err != nil {
+		http.Error(w, "db query failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	g := builder.Build(minCount)
+	if center := q.Get("center"); center != "" {
+		if g, ok = graph.Neighborhood(g, center, depth, limit); !ok {
+			http.Error(w, "concept not found", http.StatusNotFound)
+			return
+		}
+	} else {
+		g = graph.Top(g, limit)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if format == "json" {
+		_ = json.NewEncoder(w).Encode(g)
+		return
+	}
+	_ = graph.Write(w, g, format)
+}
+
+// buildGraph feeds the selected concepts of every analysis into a graph builder
+func (s *Server) buildGraph(ctx context.Context, kinds map[string]bool) (*graph.Builder, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT id, topics, keywords FROM analyses ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	concepts := make(map[string][]graph.Concept)
+	for rows.Next() {
+		var id string
+		var topics, keywords []string
+		var topicsScanner, keywordsScanner interface{} = &sqliteStringArray{&topics}, &sqliteStringArray{&keywords}
+		if s.Driver == "postgres" {
+			topicsScanner, keywordsScanner = (*pq.StringArray)(&topics), (*pq.StringArray)(&keywords)
+		}
+		if err := rows.Scan(&id, topicsScanner, keywordsScanner); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+		if kinds["topics"] {
+			for _, t := range topics {
+				concepts[id] = append(concepts[id], graph.Concept{Label: t, Kind: graph.KindTopic})
+			}
+		}
+		if kinds["keywords"] {
+			for _, k := range keywords {
+				concepts[id] = append(concepts[id], graph.Concept{Label: k, Kind: graph.KindKeyword})
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if kinds["entities"] {
+		rows, err := s.DB.QueryContext(ctx, `
+			SELECT DISTINCT m.analysis_id, e.name, e.type
+			FROM entity_mentions m
+			JOIN entities e ON e.id = m.entity_id`)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id, name, entityType string
+			if err := rows.Scan(&id, &name, &entityType); err != nil {
+				return nil, err
+			}
+			concepts[id] = append(concepts[id], graph.Concept{Label: name, Kind: entityType})
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	builder := graph.NewBuilder()
+	for _, id := range ids {
+		builder.Add(concepts[id])
+	}
+	return builder, nil
+}
+
+// parseIntParam reads an integer query parameter within [min, max]
+func parseIntParam(r *http.Request, name string, def, min, max int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("invalid %s", name)
+	}
+	return n, nil
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+	"github.com/gbengafagbola/knowledge-extractor/internal/search"
+)
+
+// hybridDepth is the minimum number of hits fetched from each retriever before fusion
+const hybridDepth = 50
+
+// HybridSearchHandler combines full-text and vector retrieval for ?q=
+// Algorithm: keyword hits + vector hits -> weighted reciprocal rank fusion -> top limit
+// Optional: keyword_weight, vector_weight (default 1 each), rrf_k (default 60), limit
+func (s *Server) HybridSearchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		http.Error(w, "missing q query param", http.StatusBadRequest)
+		return
+	}
+	limit, err := parseLimit(r, defaultSemanticResults, maxSemanticResults)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	keywordWeight, err := parseFloatParam(r, "keyword_weight", 1)
+	if err != nil || keywordWeight < 0 {
+		http.Error(w, "invalid keyword_weight", http.StatusBadRequest)
+		return
+	}
+	vectorWeight, err := parseFloatParam(r, "vector_weight", 1)
+	if err != nil || vectorWeight < 0 {
+		http.Error(w, "invalid vector_weight", http.StatusBadRequest)
+		return
+	}
+	k, err := parseFloatParam(r, "rrf_k", search.DefaultRRFK)
+	if err != nil || k <= 0 {
+		http.Error(w, "invalid rrf_k", http.StatusBadRequest)
+		return
+	}
+
+	// Fetch deeper than the page so documents ranked lower by one retriever can still surface
+	depth := 3 * limit
+	if depth < hybridDepth {
+		depth = hybridDepth
+	}
+
+	terms, lang := search.QueryTerms(q)
+	var keywordHits, vectorHits []search.Hit
+	if keywordWeight > 0 {
+		if keywordHits, err = s.keywordSearch(r.Context(), terms, lang, depth); err != nil {
+			http.Error(w, "keyword search failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if vectorWeight > 0 {
+		if vectorHits, err = s.vectorSearch(r.Context(), q, depth); err != nil {
+			http.Error(w, "semantic search failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	fused := search.FuseRRF([]search.RankedList{
+		{Name: "keyword", Weight: keywordWeight, Hits: keywordHits},
+		{Name: "vector", Weight: vectorWeight, Hits: vectorHits},
+	}, k)
+	if len(fused) > limit {
+		fused = fused[:limit]
+	}
+
+	ids := make([]string, 0, len(fused))
+	for _, h := range fused {
+		ids = append(ids, h.ID)
+	}
+	byID, err := s.loadAnalyses(r.Context(), ids)
+	if err != nil {
+		http.Error(w, "db query failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	results := make([]models.SearchResult, 0, len(fused))
+	for _, h := range fused {
+		a, ok := byID[h.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, models.SearchResult{
+			Analysis: a,
+			Score:    h.Score,
+			Explanation: &models.SearchExplanation{
+				Method:        "rrf",
+				K:             k,
+				Contributions: h.Contributions,
+				MatchedTerms:  matchedTerms(terms, lang, a),
+			},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(results)
+}
+
+// matchedTerms returns the query terms that occur in the analysis' title, summary or raw text
+func matchedTerms(terms []string, lang string, a models.Analysis) []string {
+	present := make(map[string]bool)
+	for _, tok := range analyzer.Tokenize(a.Title+"\n"+a.Summary+"\n"+a.RawText, lang) {
+		present[tok] = true
+	}
+	var matched []string
+	for _, t := range terms {
+		if present[t] {
+			matched = append(matched, t)
+		}
+	}
+	return matched
+}
+
+// parseFloatParam reads a float query param, returning def when it is absent
+func parseFloatParam(r *http.Request, name string, def float64) (float64, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid %s", name)
+	}
+	return f, nil
+}
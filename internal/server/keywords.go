@@ -0,0 +1,238 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+)
+
+const (
+	// scoredKeywords is how many corpus-weighted keywords are kept per analysis
+	scoredKeywords = 10
+	// localKeywords is how many keyphrases fill the keywords and topics fields, as many as the LLM returns
+	localKeywords = 3
+	// statsBatch caps the terms per statement, well under both drivers' parameter limits
+	statsBatch = 400
+)
+
+// scoreKeywords weights the terms of text against the stored corpus, counting
+// text as one more document (it is scored before it is inserted)
+func (s *Server) scoreKeywords(ctx context.Context, text, lang string) (analyzer.DocumentTerms, []models.KeywordScore, error) {
+	opts := s.KeywordOptions
+	opts.Language = lang
+	doc := analyzer.CountTerms(text, opts)
+
+	stats, err := s.loadCorpusStats(ctx, doc.Language, doc.Keys())
+	if err != nil {
+		return doc, nil, err
+	}
+	return doc, analyzer.ScoreKeywords(doc, stats.Include(doc), s.KeywordWeighting, scoredKeywords), nil
+}
+
+// loadCorpusStats reads the document count for lang and the document frequencies of terms
+func (s *Server) loadCorpusStats(ctx context.Context, lang string, terms []string) (analyzer.CorpusStats, error) {
+	stats := analyzer.CorpusStats{DF: make(map[string]int, len(terms))}
+	err := s.DB.QueryRowContext(ctx,
+		`SELECT documents, tokens FROM corpus_stats WHERE language = $1`, lang,
+	).Scan(&stats.Documents, &stats.Tokens)
+	if err != nil && err != sql.ErrNoRows {
+		return stats, err
+	}
+
+	for start := 0; start < len(terms); start += statsBatch {
+		batch := terms[start:min(start+statsBatch, len(terms))]
+		args := []interface{}{lang}
+		placeholders := make([]string, len(batch))
+		for i, t := range batch {
+			args = append(args, t)
+			placeholders[i] = fmt.Sprintf("$%d", i+2)
+		}
+		rows, err := s.DB.QueryContext(ctx,
+			`SELECT term, documents FROM term_stats
+			 WHERE language = $1 AND term IN (`+strings.Join(placeholders, ",")+`)`, args...)
+		if err != nil {
+			return stats, err
+		}
+		for rows.Next() {
+			var term string
+			var df int
+			if err := rows.Scan(&term, &df); err != nil {
+				rows.Close()
+				return stats, err
+			}
+			stats.DF[term] = df
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// addCorpusStats counts one more document of doc's language and each of its terms
+// Runs in the analysis insert transaction so the statistics never drift from the table
+func (s *Server) addCorpusStats(ctx context.Context, tx *sql.Tx, doc analyzer.DocumentTerms) error {
+	if err := addDocumentCounts(ctx, tx, doc.Language, 1, doc.Length); err != nil {
+		return err
+	}
+	return addTermFrequencies(ctx, tx, doc.Language, doc.Keys(), 1)
+}
+
+// addDocumentCounts adds documents and tokens to lang's totals, creating the row if missing
+// Upserts work on Postgres and SQLite 3.24+
+func addDocumentCounts(ctx context.Context, tx *sql.Tx, lang string, documents, tokens int) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO corpus_stats (language, documents, tokens) VALUES ($1, $2, $3)
+		ON CONFLICT (language) DO UPDATE SET
+			documents = corpus_stats.documents + excluded.documents,
+			tokens = corpus_stats.tokens + excluded.tokens`,
+		lang, documents, tokens)
+	return err
+}
+
+// addTermFrequencies adds df to the document frequency of each term, creating missing rows
+func addTermFrequencies(ctx context.Context, tx *sql.Tx, lang string, terms []string, df int) error {
+	for start := 0; start < len(terms); start += statsBatch {
+		batch := terms[start:min(start+statsBatch, len(terms))]
+		args := []interface{}{lang, df}
+		values := make([]string, len(batch))
+		for i, t := range batch {
+			args = append(args, t)
+			values[i] = fmt.Sprintf("($1, $2, $%d)", i+3) // SQLite numbers $N in order of first use
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO term_stats (language, documents, term) VALUES `+strings.Join(values, ",")+`
+			ON CONFLICT (language, term) DO UPDATE SET documents = term_stats.documents + excluded.documents`,
+			args...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecomputeKeywordsHandler rebuilds the corpus statistics from the stored analyses
+// and rescores every analysis' keywords against them
+// Use it after changing keyword options or weighting, or if the statistics drifted;
+// requires "Authorization: Bearer <ADMIN_TOKEN>" when an admin token is configured
+// Like inserts and deletes, it counts only analyses measured by the enrich stage (see countedCondition)
+func (s *Server) RecomputeKeywordsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.AdminToken != "" && !s.authorizedAdmin(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx := r.Context()
+	rows, err := s.DB.QueryContext(ctx, `SELECT id, raw_text, COALESCE(language, '') FROM analyses WHERE `+countedCondition)
+	if err != nil {
+		http.Error(w, "db query failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var ids []string
+	var docs []analyzer.DocumentTerms
+	for rows.Next() {
+		var id, text, lang string
+		if err := rows.Scan(&id, &text, &lang); err != nil {
+			rows.Close()
+			http.Error(w, "row scan failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		opts := s.KeywordOptions
+		opts.Language = lang
+		ids = append(ids, id)
+		docs = append(docs, analyzer.CountTerms(text, opts))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		http.Error(w, "row scan failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Aggregate per language in memory, then replace the tables in one transaction
+	stats := make(map[string]*analyzer.CorpusStats)
+	for _, doc := range docs {
+		c, ok := stats[doc.Language]
+		if !ok {
+			c = &analyzer.CorpusStats{DF: make(map[string]int)}
+			stats[doc.Language] = c
+		}
+		c.Documents++
+		c.Tokens += doc.Length
+		for key := range doc.Terms {
+			c.DF[key]++
+		}
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		http.Error(w, "failed to begin db transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if err := replaceCorpusStats(ctx, tx, stats); err != nil {
+		http.Error(w, "failed to update corpus stats: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	terms := 0
+	for _, c := range stats {
+		terms += len(c.DF)
+	}
+	for i, doc := range docs {
+		scores := analyzer.ScoreKeywords(doc, *stats[doc.Language], s.KeywordWeighting, scoredKeywords)
+		_, err := tx.ExecContext(ctx, `UPDATE analyses SET keyword_scores = $1 WHERE id = $2`, jsonValue(scores), ids[i])
+		if err != nil {
+			http.Error(w, "failed to update keyword scores: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "failed to commit db transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"documents": len(docs),
+		"terms":     terms,
+		"weighting": s.KeywordWeighting,
+	})
+}
+
+// replaceCorpusStats swaps the statistics tables' contents for stats
+func replaceCorpusStats(ctx context.Context, tx *sql.Tx, stats map[string]*analyzer.CorpusStats) error {
+	for _, stmt := range []string{`DELETE FROM term_stats`, `DELETE FROM corpus_stats`} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	for lang, c := range stats {
+		// Group terms by frequency so each batch shares one df parameter
+		byDF := make(map[int][]string)
+		for term, df := range c.DF {
+			byDF[df] = append(byDF[df], term)
+		}
+		if err := addDocumentCounts(ctx, tx, lang, c.Documents, c.Tokens); err != nil {
+			return err
+		}
+		for df, terms := range byDF {
+			if err := addTermFrequencies(ctx, tx, lang, terms, df); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,362 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
+	"github.com/gbengafagbola/knowledge-extractor/internal/llm"
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+)
+
+// Analysis fields the pipeline fills, as named in the JSON response and in Sources
+const (
+	FieldSummary   = "summary"
+	FieldTitle     = "title"
+	FieldTopics    = "topics"
+	FieldSentiment = "sentiment"
+	FieldKeywords  = "keywords"
+	FieldEntities  = "entities"
+)
+
+// Sources recorded per field
+const (
+	SourceLLM    = "llm"    // Produced by the LLM provider (see Analysis.Provider)
+	SourceLocal  = "local"  // Produced by an extractor in the analyzer package
+	SourceMerged = "merged" // LLM items followed by local ones
+	SourceHuman  = "human"  // Edited through PATCH /analyses/{id}
+)
+
+// mockProvider is the provider name of llm.MockClient, also reported after a ResilientClient fallback
+const mockProvider = "mock"
+
+// FieldStrategy decides how LLM and local output combine for one field
+type FieldStrategy string
+
+const (
+	StrategyLLM   FieldStrategy = "llm"   // LLM output; local output only fills it when empty
+	StrategyLocal FieldStrategy = "local" // Local output replaces the LLM's when there is any
+	StrategyMerge FieldStrategy = "merge" // List fields: both, deduplicated case-insensitively; others as llm
+)
+
+// pipelineField is a field that has a local extractor; exactly one accessor is set
+type pipelineField struct {
+	name   string
+	list   func(*models.Analysis) *[]string
+	scalar func(*models.Analysis) *string
+}
+
+// pipelineFields lists the fields with local extractors; the others always come from the LLM
+var pipelineFields = []pipelineField{
+	{name: FieldSummary, scalar: func(a *models.Analysis) *string { return &a.Summary }},
+	{name: FieldTopics, list: func(a *models.Analysis) *[]string { return &a.Topics }},
+	{name: FieldSentiment, scalar: func(a *models.Analysis) *string { return &a.Sentiment }},
+	{name: FieldKeywords, list: func(a *models.Analysis) *[]string { return &a.Keywords }},
+}
+
+// DefaultFieldStrategies extracts keywords locally and takes everything else from the LLM
+func DefaultFieldStrategies() map[string]FieldStrategy {
+	return map[string]FieldStrategy{FieldKeywords: StrategyLocal}
+}
+
+// ParseFieldStrategies parses "keywords=local,topics=merge" into per-field strategies
+func ParseFieldStrategies(spec string) (map[string]FieldStrategy, error) {
+	strategies := make(map[string]FieldStrategy)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected field=strategy, got %q", part)
+		}
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !hasLocalExtractor(field) {
+			return nil, fmt.Errorf("field %q has no local extractor", field)
+		}
+		switch strategy := FieldStrategy(strings.ToLower(strings.TrimSpace(value))); strategy {
+		case StrategyLLM, StrategyLocal, StrategyMerge:
+			strategies[field] = strategy
+		default:
+			return nil, fmt.Errorf("unknown strategy %q for %s", value, field)
+		}
+	}
+	return strategies, nil
+}
+
+func hasLocalExtractor(field string) bool {
+	for _, f := range pipelineFields {
+		if f.name == field {
+			return true
+		}
+	}
+	return false
+}
+
+// strategy returns the configured strategy for field, StrategyLLM by default
+func (s *Server) strategy(field string) FieldStrategy {
+	if st, ok := s.FieldStrategies[field]; ok {
+		return st
+	}
+	return StrategyLLM
+}
+
+// Analysis stages of this package, in the order of the default pipeline
+// between the analyzer stages (normalize, language, injection, validate)
+const (
+	StageRedact      = "redact"
+	StageDeduplicate = "deduplicate"
+	StageLocal       = "local"
+	StageLLM         = "llm"
+	StageEntities    = "entities"
+	StageEnrich      = "enrich"
+)
+
+// requestStages may be disabled or given another error policy by a single request; the
+// other stages enforce the server's normalization, redaction, injection and duplicate policies
+var requestStages = map[string]bool{StageLLM: true, StageEntities: true, analyzer.StageValidate: true, StageEnrich: true}
+
+// DefaultPipeline is the analysis of POST /analyze: normalize -> redact -> language ->
+// injection -> deduplicate -> local -> llm -> entities -> validate -> enrich
+// Every stage fails the request on error until its policy is changed
+func (s *Server) DefaultPipeline() *analyzer.Pipeline {
+	return analyzer.NewPipeline(
+		analyzer.NormalizeStage(),
+		analyzer.NewStage(StageRedact, s.redactStage),
+		analyzer.LanguageStage(),
+		analyzer.InjectionStage(),
+		analyzer.NewStage(StageDeduplicate, s.deduplicateStage),
+		analyzer.NewStage(StageLocal, s.localStage),
+		llmStage{s},
+		analyzer.NewStage(StageEntities, s.entitiesStage),
+		analyzer.ValidateStage(),
+		analyzer.NewStage(StageEnrich, s.enrichStage),
+	)
+}
+
+// ParseStagePolicies parses "llm=degrade,enrich=skip" into per-stage error policies
+func ParseStagePolicies(spec string) (map[string]analyzer.ErrorPolicy, error) {
+	policies := make(map[string]analyzer.ErrorPolicy)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		stage, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected stage=policy, got %q", part)
+		}
+		policy, ok := analyzer.ParseErrorPolicy(value)
+		if !ok {
+			return nil, fmt.Errorf("unknown policy %q for %s", value, stage)
+		}
+		policies[strings.ToLower(strings.TrimSpace(stage))] = policy
+	}
+	return policies, nil
+}
+
+// PRIVACY: Personal data is replaced before the text reaches the LLM or the database
+// Everything after this stage works on the redacted text; offsets refer to it
+func (s *Server) redactStage(ctx context.Context, doc *analyzer.Document) error {
+	if s.Redactor == nil {
+		return nil
+	}
+	redaction := s.Redactor.Redact(doc.Text)
+	doc.Text = redaction.Text
+	doc.Analysis.Redactions = redaction.Redactions
+	doc.RedactionTokens = redaction.Tokens
+	return nil
+}
+
+// DEDUPLICATION: Feeds re-submit lightly edited copies; compare signatures before paying for the LLM
+// A near copy fails the stage with a duplicateError under the link and reject policies
+func (s *Server) deduplicateStage(ctx context.Context, doc *analyzer.Document) error {
+	signature := analyzer.MinHash(doc.Text, doc.Language)
+	canonical, err := s.findCanonical(ctx, signature)
+	if err != nil {
+		return fmt.Errorf("failed to look up duplicates: %w", err)
+	}
+	if canonical != "" && s.DuplicatePolicy != DuplicateStore {
+		return &duplicateError{canonical: canonical}
+	}
+	doc.Signature = &signature
+	doc.Analysis.DuplicateOf = canonical
+	return nil
+}
+
+// duplicateError stops the pipeline for a near copy the duplicate policy does not store,
+// whatever the stage's error policy
+type duplicateError struct {
+	canonical string
+}
+
+func (e *duplicateError) Error() string { return "near duplicate of analysis " + e.canonical }
+
+func (e *duplicateError) Terminal() bool { return true }
+
+// localStage runs the analyzer extractors; their output fills the fields until the LLM stage
+// combines it with its own, so the fields stay local when that stage is disabled or skipped
+func (s *Server) localStage(ctx context.Context, doc *analyzer.Document) error {
+	lang := doc.Language
+	opts := s.KeywordOptions
+	opts.Language = lang
+	var local models.Analysis
+	for _, p := range analyzer.ExtractKeyphrases(doc.Text, localKeywords, s.KeywordExtractor, opts) {
+		local.Keywords = append(local.Keywords, p.Text)
+	}
+	// Topics are broader than keywords: TextRank phrases, ranked by centrality
+	for _, p := range analyzer.ExtractKeyphrases(doc.Text, localKeywords, analyzer.KeyphraseTextRank, opts) {
+		local.Topics = append(local.Topics, p.Text)
+	}
+	summary := s.SummaryOptions
+	summary.Language = lang
+	local.Summary = analyzer.SummarizeText(doc.Text, summary)
+	if scores, ok := analyzer.ScoreSentiment(doc.Text, lang); ok {
+		local.Sentiment = scores.Label
+		local.SentimentScores = &scores
+		local.SentimentScore = scores.Compound
+	}
+
+	doc.Local = local
+	a := &doc.Analysis
+	if a.Sources == nil {
+		a.Sources = make(map[string]string)
+	}
+	for _, f := range pipelineFields {
+		if f.list != nil && len(*f.list(&local)) > 0 {
+			*f.list(a), a.Sources[f.name] = *f.list(&local), SourceLocal
+		}
+		if f.scalar != nil && *f.scalar(&local) != "" {
+			*f.scalar(a), a.Sources[f.name] = *f.scalar(&local), SourceLocal
+		}
+	}
+	a.SentimentScore, a.SentimentScores = local.SentimentScore, local.SentimentScores
+	return nil
+}
+
+// llmStage calls the LLM and combines its output with the local extractors' per field strategy
+type llmStage struct {
+	s *Server
+}
+
+func (st llmStage) Name() string { return StageLLM }
+
+// Run: the handler doesn't know or care which LLM implementation is used
+func (st llmStage) Run(ctx context.Context, doc *analyzer.Document) error {
+	s := st.s
+	opts := llm.AnalyzeOptions{TargetLanguage: doc.TargetLanguage}
+	if doc.Language != analyzer.LanguageUndetermined {
+		opts.SourceLanguage = doc.Language
+	}
+	result, err := llm.Analyze(s.LLM, doc.Text, opts)
+	if err != nil {
+		return err
+	}
+	// The mock's summary is a placeholder; with no real LLM an extractive one takes its place
+	if result.Provider == mockProvider {
+		result.Summary = ""
+	}
+	// An unknown label is no label, so the local one can fill in
+	result.Sentiment = strings.ToLower(strings.TrimSpace(result.Sentiment))
+	if !analyzer.IsSentimentLabel(result.Sentiment) {
+		result.Sentiment = ""
+	}
+
+	fromLLM := models.Analysis{Summary: result.Summary, Topics: result.Topics, Sentiment: result.Sentiment, Keywords: result.Keywords}
+	a := &doc.Analysis
+	if a.Sources == nil {
+		a.Sources = make(map[string]string)
+	}
+	a.Title, a.Sources[FieldTitle] = result.Title, SourceLLM
+	a.Confidence = result.Confidence
+	a.Provider = result.Provider
+	for _, f := range pipelineFields {
+		if f.list != nil {
+			*f.list(a), a.Sources[f.name] = combine(s.strategy(f.name), *f.list(&fromLLM), *f.list(&doc.Local))
+		} else {
+			*f.scalar(a), a.Sources[f.name] = combineScalar(s.strategy(f.name), *f.scalar(&fromLLM), *f.scalar(&doc.Local))
+		}
+	}
+
+	// Cross-check: flag an LLM label the lexicon clearly disagrees with
+	if a.SentimentScores != nil && a.Sources[FieldSentiment] == SourceLLM {
+		scores := *a.SentimentScores
+		scores.Conflict = analyzer.SentimentConflict(a.Sentiment, scores)
+		a.SentimentScores = &scores
+	}
+	return nil
+}
+
+// Degrade keeps the local fields and titles the analysis with its most central sentence
+func (st llmStage) Degrade(ctx context.Context, doc *analyzer.Document, err error) error {
+	headline := analyzer.SummarizeText(doc.Text, analyzer.SummaryOptions{Sentences: 1, MaxChars: 80, Language: doc.Language})
+	if headline != "" && doc.Analysis.Title == "" {
+		if doc.Analysis.Sources == nil {
+			doc.Analysis.Sources = make(map[string]string)
+		}
+		doc.Analysis.Title, doc.Analysis.Sources[FieldTitle] = headline, SourceLocal
+	}
+	return nil
+}
+
+// entitiesStage extracts named entities with the LLM when it can, locally otherwise
+func (s *Server) entitiesStage(ctx context.Context, doc *analyzer.Document) error {
+	entities, source := s.extractEntities(doc.Text)
+	if doc.Analysis.Sources == nil {
+		doc.Analysis.Sources = make(map[string]string)
+	}
+	doc.Analysis.Entities, doc.Analysis.Sources[FieldEntities] = entities, source
+	return nil
+}
+
+// RELEVANCE: Weight local keywords by how rare they are across stored analyses,
+// measure the text and embed it for semantic search
+func (s *Server) enrichStage(ctx context.Context, doc *analyzer.Document) error {
+	stats := analyzer.MeasureText(doc.Text, doc.Language)
+	terms, keywordScores, err := s.scoreKeywords(ctx, doc.Text, doc.Language)
+	if err != nil {
+		return fmt.Errorf("failed to load corpus stats: %w", err)
+	}
+	doc.Analysis.TextStats = &stats
+	doc.Analysis.KeywordScores = keywordScores
+	doc.Terms = terms
+	doc.Vector = s.embed(doc.Text)
+	return nil
+}
+
+// combineScalar applies strategy to one single-valued field; merge keeps the LLM's value
+func combineScalar(strategy FieldStrategy, fromLLM, fromLocal string) (string, string) {
+	if fromLocal != "" && (fromLLM == "" || strategy == StrategyLocal) {
+		return fromLocal, SourceLocal
+	}
+	return fromLLM, SourceLLM
+}
+
+// combine applies strategy to one list field
+func combine(strategy FieldStrategy, fromLLM, fromLocal []string) ([]string, string) {
+	switch {
+	case len(fromLocal) == 0:
+		return fromLLM, SourceLLM
+	case len(fromLLM) == 0, strategy == StrategyLocal:
+		return fromLocal, SourceLocal
+	case strategy == StrategyMerge:
+		merged := append([]string{}, fromLLM...)
+		seen := make(map[string]bool, len(fromLLM))
+		for _, v := range fromLLM {
+			seen[strings.ToLower(v)] = true
+		}
+		for _, v := range fromLocal {
+			if !seen[strings.ToLower(v)] {
+				seen[strings.ToLower(v)] = true
+				merged = append(merged, v)
+			}
+		}
+		if len(merged) == len(fromLLM) {
+			return fromLLM, SourceLLM
+		}
+		return merged, SourceMerged
+	}
+	return fromLLM, SourceLLM
+}
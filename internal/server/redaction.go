@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/redact"
+)
+
+// ReidentifyHandler returns an analysis with tokenized personal data restored
+// Requires "Authorization: Bearer <ADMIN_TOKEN>"; only values redacted with the
+// tokenize policy can be restored (masked and hashed values are gone for good)
+func (s *Server) ReidentifyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.AdminToken == "" || s.Redactor == nil || !s.Redactor.Tokenizes() {
+		http.Error(w, "re-identification is disabled", http.StatusForbidden)
+		return
+	}
+	if !s.authorizedAdmin(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "missing id query param", http.StatusBadRequest)
+		return
+	}
+	byID, err := s.loadAnalyses(r.Context(), []string{id})
+	if err != nil {
+		http.Error(w, "db query failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	analysis, ok := byID[id]
+	if !ok {
+		http.Error(w, "analysis not found", http.StatusNotFound)
+		return
+	}
+
+	var sealed []byte
+	err = s.DB.QueryRowContext(r.Context(),
+		`SELECT sealed_map FROM redaction_maps WHERE analysis_id = $1`, id).Scan(&sealed)
+	if err != nil && err != sql.ErrNoRows {
+		http.Error(w, "db query failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err == nil {
+		tokens, err := s.Redactor.OpenTokens(id, sealed)
+		if err != nil {
+			http.Error(w, "failed to decrypt redaction map", http.StatusInternalServerError)
+			return
+		}
+		analysis.RawText = redact.Restore(analysis.RawText, tokens)
+		analysis.Summary = redact.Restore(analysis.Summary, tokens)
+		analysis.Title = redact.Restore(analysis.Title, tokens)
+		for i := range analysis.Topics {
+			analysis.Topics[i] = redact.Restore(analysis.Topics[i], tokens)
+		}
+		for i := range analysis.Keywords {
+			analysis.Keywords[i] = redact.Restore(analysis.Keywords[i], tokens)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(analysis)
+}
+
+// authorizedAdmin checks the bearer token in constant time
+func (s *Server) authorizedAdmin(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.AdminToken)) == 1
+}
+
+// storeRedactionMap encrypts and stores the token map of a tokenized analysis
+func (s *Server) storeRedactionMap(ctx context.Context, tx *sql.Tx, analysisID string, tokens map[string]string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	sealed, err := s.Redactor.SealTokens(analysisID, tokens)
+	if err != nil {
+		return fmt.Errorf("seal redaction map: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO redaction_maps (analysis_id, sealed_map) VALUES ($1,$2)`, analysisID, sealed); err != nil {
+		return fmt.Errorf("insert redaction map: %w", err)
+	}
+	return nil
+}
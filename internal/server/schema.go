@@ -0,0 +1,437 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+)
+
+// column is a column added to an existing table after its initial release
+type column struct {
+	name         string
+	postgresType string
+	sqliteType   string
+}
+
+// analysisColumnUpgrades are added to analyses on startup when missing,
+// so databases created by older versions pick up new fields
+var analysisColumnUpgrades = []column{
+	{"language", "TEXT", "TEXT"},
+	{"injection_suspected", "BOOLEAN NOT NULL DEFAULT false", "INTEGER NOT NULL DEFAULT 0"},
+	{"injection_score", "NUMERIC", "REAL"},
+	{"grounding", "JSONB", "TEXT"},
+	{"keyword_scores", "JSONB", "TEXT"},
+	{"provider", "TEXT", "TEXT"},
+	{"field_sources", "JSONB", "TEXT"},
+	{"sentiment_score", "NUMERIC", "REAL"},
+	{"sentiment_scores", "JSONB", "TEXT"},
+	{"text_stats", "JSONB", "TEXT"},
+	{"word_count", "INTEGER", "INTEGER"},
+	{"sentence_count", "INTEGER", "INTEGER"},
+	{"reading_ease", "NUMERIC", "REAL"},
+	{"grade_level", "NUMERIC", "REAL"},
+	{"minhash", "BYTEA", "BLOB"},
+	{"duplicate_of", "TEXT", "TEXT"},
+	{"updated_at", "TIMESTAMP WITH TIME ZONE", "TIMESTAMP"},
+	{"terms_indexed_at", "TIMESTAMP WITH TIME ZONE", "TIMESTAMP"},
+}
+
+// Migrate creates the tables for the configured driver if they don't exist
+// Every statement is idempotent, so it is safe to run on each startup
+func (s *Server) Migrate() error {
+	for _, stmt := range s.schemaStatements() {
+		if _, err := s.DB.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to migrate schema: %w", err)
+		}
+	}
+
+	if err := s.addMissingColumns("analyses", analysisColumnUpgrades); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
+	if err := s.migrateTextStats(); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
+	if err := s.migrateFingerprints(); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
+	if err := s.migrateEmbeddings(); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
+	if err := s.migrateFullText(); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
+	if err := s.migrateTerms(); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
+	fmt.Println("Database schema created/verified successfully")
+	return nil
+}
+
+// addMissingColumns adds each column to table unless it already exists
+// Postgres supports ADD COLUMN IF NOT EXISTS; SQLite needs a PRAGMA lookup first
+func (s *Server) addMissingColumns(table string, columns []column) error {
+	if s.Driver == "postgres" {
+		for _, c := range columns {
+			stmt := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s`, table, c.name, c.postgresType)
+			if _, err := s.DB.Exec(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	rows, err := s.DB.Query(fmt.Sprintf(`SELECT name FROM pragma_table_info('%s')`, table))
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range columns {
+		if existing[c.name] {
+			continue
+		}
+		stmt := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, c.name, c.sqliteType)
+		if _, err := s.DB.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateTextStats indexes word counts and measures analyses stored before text statistics existed
+func (s *Server) migrateTextStats() error {
+	if _, err := s.DB.Exec(`CREATE INDEX IF NOT EXISTS idx_analyses_word_count ON analyses (word_count);`); err != nil {
+		return err
+	}
+
+	rows, err := s.DB.Query(`SELECT id, raw_text, COALESCE(language, '') FROM analyses WHERE text_stats IS NULL`)
+	if err != nil {
+		return err
+	}
+	measured := make(map[string]models.TextStats)
+	for rows.Next() {
+		var id, text, lang string
+		if err := rows.Scan(&id, &text, &lang); err != nil {
+			rows.Close()
+			return err
+		}
+		measured[id] = analyzer.MeasureText(text, lang)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, stats := range measured {
+		_, err := s.DB.Exec(`
+			UPDATE analyses SET text_stats = $1, word_count = $2, sentence_count = $3, reading_ease = $4, grade_level = $5
+			WHERE id = $6`,
+			jsonValue(stats), stats.Words, stats.Sentences, stats.ReadingEase, stats.GradeLevel, id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateFingerprints signs analyses stored before duplicate detection existed,
+// so new submissions are compared against them too
+func (s *Server) migrateFingerprints() error {
+	rows, err := s.DB.Query(`SELECT id, raw_text, COALESCE(language, '') FROM analyses WHERE minhash IS NULL`)
+	if err != nil {
+		return err
+	}
+	signatures := make(map[string]analyzer.Signature)
+	for rows.Next() {
+		var id, text, lang string
+		if err := rows.Scan(&id, &text, &lang); err != nil {
+			rows.Close()
+			return err
+		}
+		signatures[id] = analyzer.MinHash(text, lang)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, sig := range signatures {
+		tx, err := s.DB.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE analyses SET minhash = $1 WHERE id = $2`, sig.Bytes(), id); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := storeFingerprint(context.Background(), tx, id, sig); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateEmbeddings creates the embeddings table. On Postgres it uses pgvector when
+// the extension can be enabled; otherwise vectors are stored as bytes and served
+// from the local in-memory index, as on SQLite
+func (s *Server) migrateEmbeddings() error {
+	embeddingType := "BLOB"
+	if s.Driver == "postgres" {
+		embeddingType = "BYTEA"
+		if _, err := s.DB.Exec(`CREATE EXTENSION IF NOT EXISTS vector`); err == nil {
+			embeddingType = "vector"
+		} else {
+			fmt.Println("pgvector unavailable, using local vector index:", err)
+		}
+	}
+
+	stmts := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS analysis_embeddings (
+			analysis_id TEXT PRIMARY KEY REFERENCES analyses(id) ON DELETE CASCADE,
+			model TEXT NOT NULL,
+			embedding %s NOT NULL
+		);`, embeddingType),
+		`CREATE INDEX IF NOT EXISTS idx_analysis_embeddings_model ON analysis_embeddings (model);`,
+	}
+	for _, stmt := range stmts {
+		if _, err := s.DB.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	if s.Driver != "postgres" {
+		return nil
+	}
+
+	// The table may predate pgvector being enabled, so trust the actual column type
+	var udt string
+	err := s.DB.QueryRow(`
+		SELECT udt_name FROM information_schema.columns
+		WHERE table_name = 'analysis_embeddings' AND column_name = 'embedding'`).Scan(&udt)
+	if err != nil {
+		return err
+	}
+	if udt == "vector" {
+		s.Vectors = nil
+	}
+	return nil
+}
+
+// migrateFullText adds the keyword index over title, summary and raw text
+// Postgres: a weighted tsvector generated column with a GIN index
+// SQLite: an FTS5 table when the driver is built with -tags sqlite_fts5, FTS4 otherwise,
+// kept in sync with analyses by triggers
+func (s *Server) migrateFullText() error {
+	if s.Driver == "postgres" {
+		for _, stmt := range []string{
+			`ALTER TABLE analyses ADD COLUMN IF NOT EXISTS search_vector tsvector
+				GENERATED ALWAYS AS (
+					setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+					setweight(to_tsvector('simple', coalesce(summary, '')), 'B') ||
+					setweight(to_tsvector('simple', coalesce(raw_text, '')), 'C')
+				) STORED;`,
+			`CREATE INDEX IF NOT EXISTS idx_analyses_search_vector ON analyses USING GIN (search_vector);`,
+		} {
+			if _, err := s.DB.Exec(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	module, err := s.sqliteFTSModule()
+	if err != nil {
+		return err
+	}
+	if module == "" {
+		// The FTS table stores its own copy keyed by id (rowids of analyses are not stable)
+		_, err := s.DB.Exec(`CREATE VIRTUAL TABLE analyses_fts USING fts5(
+			id UNINDEXED, title, summary, raw_text, tokenize = 'unicode61 remove_diacritics 2')`)
+		if err != nil {
+			fmt.Println("FTS5 unavailable, using FTS4 for full-text search:", err)
+			_, err = s.DB.Exec(`CREATE VIRTUAL TABLE analyses_fts USING fts4(
+				id, title, summary, raw_text, notindexed=id, tokenize=unicode61 "remove_diacritics=2")`)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = s.DB.Exec(`INSERT INTO analyses_fts (id, title, summary, raw_text)
+			SELECT id, title, summary, raw_text FROM analyses`)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, stmt := range []string{
+		`CREATE TRIGGER IF NOT EXISTS analyses_fts_insert AFTER INSERT ON analyses BEGIN
+			INSERT INTO analyses_fts (id, title, summary, raw_text)
+			VALUES (new.id, new.title, new.summary, new.raw_text);
+		END;`,
+		`CREATE TRIGGER IF NOT EXISTS analyses_fts_delete AFTER DELETE ON analyses BEGIN
+			DELETE FROM analyses_fts WHERE id = old.id;
+		END;`,
+		`CREATE TRIGGER IF NOT EXISTS analyses_fts_update AFTER UPDATE OF title, summary, raw_text ON analyses BEGIN
+			DELETE FROM analyses_fts WHERE id = old.id;
+			INSERT INTO analyses_fts (id, title, summary, raw_text)
+			VALUES (new.id, new.title, new.summary, new.raw_text);
+		END;`,
+	} {
+		if _, err := s.DB.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqliteFTSModule reports which FTS module backs analyses_fts ("fts5", "fts4"),
+// or "" when the table doesn't exist yet
+func (s *Server) sqliteFTSModule() (string, error) {
+	var ddl string
+	err := s.DB.QueryRow(`SELECT sql FROM sqlite_master WHERE name = 'analyses_fts'`).Scan(&ddl)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(strings.ToLower(ddl), "fts5") {
+		return "fts5", nil
+	}
+	return "fts4", nil
+}
+
+func (s *Server) schemaStatements() []string {
+	var analysesSQL string
+	blobType, jsonType, realType := "BLOB", "TEXT", "REAL"
+	timestampType := "TIMESTAMP DEFAULT CURRENT_TIMESTAMP"
+	if s.Driver == "postgres" {
+		blobType, jsonType, realType = "BYTEA", "JSONB", "NUMERIC"
+		timestampType = "TIMESTAMP WITH TIME ZONE DEFAULT now()"
+		analysesSQL = `
+			CREATE TABLE IF NOT EXISTS analyses (
+				id TEXT PRIMARY KEY,
+				raw_text TEXT NOT NULL,
+				summary TEXT,
+				title TEXT,
+				topics TEXT[],
+				sentiment TEXT,
+				keywords TEXT[],
+				confidence NUMERIC,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+			);`
+	} else {
+		// SQLite version - arrays stored as JSON text ('["Go","Data, Storage"]'); sqliteStringArray
+		// also reads the Postgres array literals and comma-separated strings of older rows
+		analysesSQL = `
+			CREATE TABLE IF NOT EXISTS analyses (
+				id TEXT PRIMARY KEY,
+				raw_text TEXT NOT NULL,
+				summary TEXT,
+				title TEXT,
+				topics TEXT,
+				sentiment TEXT,
+				keywords TEXT,
+				confidence REAL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);`
+	}
+
+	return []string{
+		analysesSQL,
+		// GET /analyses pages through created_at with id as the tie-breaker
+		`CREATE INDEX IF NOT EXISTS idx_analyses_created_at ON analyses (created_at, id);`,
+		// Entities are shared across documents; mentions link them to analyses
+		`CREATE TABLE IF NOT EXISTS entities (
+			id TEXT PRIMARY KEY,
+			type TEXT NOT NULL,
+			name TEXT NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS entity_mentions (
+			analysis_id TEXT NOT NULL REFERENCES analyses(id) ON DELETE CASCADE,
+			entity_id TEXT NOT NULL REFERENCES entities(id),
+			mention TEXT NOT NULL,
+			start_offset INTEGER NOT NULL,
+			end_offset INTEGER NOT NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_entities_name ON entities (lower(name));`,
+		`CREATE INDEX IF NOT EXISTS idx_entity_mentions_entity ON entity_mentions (entity_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_entity_mentions_analysis ON entity_mentions (analysis_id);`,
+		// Token -> original value maps of tokenized analyses, AES-GCM encrypted
+		`CREATE TABLE IF NOT EXISTS redaction_maps (
+			analysis_id TEXT PRIMARY KEY REFERENCES analyses(id) ON DELETE CASCADE,
+			sealed_map ` + blobType + ` NOT NULL
+		);`,
+		// Document frequencies for corpus-aware keyword scoring, per language;
+		// updated on every insert and rebuilt by POST /keywords/recompute
+		`CREATE TABLE IF NOT EXISTS corpus_stats (
+			language TEXT PRIMARY KEY,
+			documents INTEGER NOT NULL DEFAULT 0,
+			tokens INTEGER NOT NULL DEFAULT 0
+		);`,
+		`CREATE TABLE IF NOT EXISTS term_stats (
+			language TEXT NOT NULL,
+			term TEXT NOT NULL,
+			documents INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (language, term)
+		);`,
+		// MinHash LSH bands for near-duplicate lookup; one row per band and analysis
+		`CREATE TABLE IF NOT EXISTS analysis_fingerprints (
+			analysis_id TEXT NOT NULL REFERENCES analyses(id) ON DELETE CASCADE,
+			band INTEGER NOT NULL,
+			value BIGINT NOT NULL,
+			PRIMARY KEY (band, value, analysis_id)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_analysis_fingerprints_analysis ON analysis_fingerprints (analysis_id);`,
+		// Corpus themes from the last POST /clusters run, replaced wholesale on each run
+		`CREATE TABLE IF NOT EXISTS clusters (
+			id TEXT PRIMARY KEY,
+			label TEXT NOT NULL,
+			label_source TEXT NOT NULL,
+			terms ` + jsonType + `,
+			created_at ` + timestampType + `
+		);`,
+		`CREATE TABLE IF NOT EXISTS cluster_members (
+			cluster_id TEXT NOT NULL REFERENCES clusters(id) ON DELETE CASCADE,
+			analysis_id TEXT NOT NULL REFERENCES analyses(id) ON DELETE CASCADE,
+			similarity ` + realType + ` NOT NULL,
+			PRIMARY KEY (cluster_id, analysis_id)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_cluster_members_analysis ON cluster_members (analysis_id);`,
+		// Topics and keywords, one row each in their original order, matched by folded term_key;
+		// the topics and keywords columns still hold the lists returned with each analysis
+		`CREATE TABLE IF NOT EXISTS analysis_terms (
+			analysis_id TEXT NOT NULL REFERENCES analyses(id) ON DELETE CASCADE,
+			kind TEXT NOT NULL,
+			position INTEGER NOT NULL,
+			term TEXT NOT NULL,
+			term_key TEXT NOT NULL,
+			PRIMARY KEY (analysis_id, kind, position)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_analysis_terms_key ON analysis_terms (term_key, kind);`,
+	}
+}
@@ -0,0 +1,387 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+	"github.com/gbengafagbola/knowledge-extractor/internal/search"
+)
+
+// Topic matching modes of /search (?topic_match=)
+const (
+	matchAny = "any"
+	matchAll = "all"
+)
+
+// searchSorts are the values of /search?sort=: the /analyses columns, date as another
+// name for created_at, and relevance (best first) for full-text queries
+var searchSorts = map[string]sortColumn{
+	"created_at": sortColumns["created_at"],
+	"date":       sortColumns["created_at"],
+	"confidence": sortColumns["confidence"],
+	"title":      sortColumns["title"],
+	"relevance": {
+		expr:    "h.score",
+		key:     func(r models.SearchResult) string { return strconv.FormatFloat(r.Score, 'g', -1, 64) },
+		param:   floatParam,
+		reverse: true,
+	},
+}
+
+// searchRequest is a parsed /search request
+type searchRequest struct {
+	query      search.Query  // Full-text query; no clauses without ?q=
+	conditions []string      // Filters on analyses, all of which must hold
+	args       []interface{} // Parameters of conditions; with ?q=, args[0] is left for the match expression
+	order      sortOrder
+	cursor     *pageCursor
+	limit      int
+}
+
+// parseSearchRequest reads the /search params into SQL conditions
+// Conditions number their parameters in the order they appear, which SQLite requires
+func (s *Server) parseSearchRequest(r *http.Request) (searchRequest, error) {
+	params := r.URL.Query()
+	var req searchRequest
+	// add appends a condition, a format with one $%[i]d per value, and its values
+	add := func(condition string, values ...interface{}) {
+		refs := make([]interface{}, len(values))
+		for i, v := range values {
+			req.args = append(req.args, v)
+			refs[i] = len(req.args)
+		}
+		req.conditions = append(req.conditions, fmt.Sprintf(condition, refs...))
+	}
+
+	if q := strings.TrimSpace(params.Get("q")); q != "" {
+		if req.query = search.ParseQuery(q); len(req.query.Clauses) == 0 {
+			return req, fmt.Errorf("q has no words to search for")
+		}
+		req.args = append(req.args, nil)
+	}
+
+	var topics []interface{}
+	for _, t := range params["topic"] {
+		if key := termKey(t); key != "" {
+			topics = append(topics, key)
+		}
+	}
+	switch mode := params.Get("topic_match"); {
+	case len(topics) == 0:
+	case mode == "" || mode == matchAny:
+		add(termCondition("", len(topics)), topics...)
+	case mode == matchAll:
+		for _, t := range topics {
+			add(termCondition("", 1), t)
+		}
+	default:
+		return req, fmt.Errorf("invalid topic_match (any or all)")
+	}
+	for _, tag := range params["tag"] {
+		if key := termKey(tag); key != "" {
+			add(termCondition(termKeyword, 1), key)
+		}
+	}
+	if entity := params.Get("entity"); entity != "" {
+		add(entityCondition, entity)
+	}
+
+	if v := params.Get("sentiment"); v != "" {
+		var labels []interface{}
+		for _, label := range strings.Split(v, ",") {
+			label = strings.ToLower(strings.TrimSpace(label))
+			if !analyzer.IsSentimentLabel(label) {
+				return req, fmt.Errorf("invalid sentiment (positive, neutral or negative)")
+			}
+			labels = append(labels, label)
+		}
+		add(`lower(sentiment) IN (`+placeholders(len(labels))+`)`, labels...)
+	}
+	if v := params.Get("provider"); v != "" {
+		add(`provider = $%[1]d`, v)
+	}
+	if v := params.Get("language"); v != "" {
+		lang, ok := analyzer.NormalizeLanguage(v)
+		if !ok {
+			return req, fmt.Errorf("invalid language")
+		}
+		add(`language = $%[1]d`, lang)
+	}
+	if params.Get("min_confidence") != "" {
+		v, err := parseFloatParam(r, "min_confidence", 0)
+		if err != nil {
+			return req, err
+		}
+		add(`confidence >= $%[1]d`, v)
+	}
+
+	loc := time.UTC
+	if tz := params.Get("tz"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return req, fmt.Errorf("invalid tz")
+		}
+	}
+	for _, bound := range []struct{ param, op string }{{"from", ">="}, {"to", "<"}} {
+		if v := params.Get(bound.param); v != "" {
+			t, err := parseTrendTime(v, loc)
+			if err != nil {
+				return req, fmt.Errorf("invalid %s", bound.param)
+			}
+			add(`created_at `+bound.op+` $%[1]d`, s.timeParam(t))
+		}
+	}
+
+	filters, err := parseStatsFilters(r)
+	if err != nil {
+		return req, err
+	}
+	for _, f := range filters {
+		add(f.column+` `+f.op+` $%[1]d`, f.value)
+	}
+
+	if len(req.query.Clauses) == 0 && len(req.conditions) == 0 {
+		return req, fmt.Errorf("missing q, topic, entity or filter query param")
+	}
+
+	defaultSort := defaultAnalysesSort
+	if len(req.query.Clauses) > 0 {
+		defaultSort = "relevance"
+	}
+	if req.order, err = parseSort(params.Get("sort"), defaultSort, searchSorts); err != nil {
+		return req, err
+	}
+	if req.order.name == "relevance" && len(req.query.Clauses) == 0 {
+		return req, fmt.Errorf("sort=relevance needs q")
+	}
+	if v := params.Get("cursor"); v != "" {
+		cursor, err := decodeCursor(v, req.order)
+		if err != nil {
+			return req, err
+		}
+		req.cursor = &cursor
+	}
+	req.limit, err = parseLimit(r, defaultAnalysesPage, maxAnalysesPage)
+	return req, err
+}
+
+// termCondition matches analyses having any of n terms of kind, or of either kind when
+// kind is empty; terms are termKeys
+func termCondition(kind string, n int) string {
+	condition := `id IN (SELECT analysis_id FROM analysis_terms WHERE term_key IN (` + placeholders(n) + `)`
+	if kind != "" {
+		condition += ` AND kind = '` + kind + `'`
+	}
+	return condition + `)`
+}
+
+// placeholders returns a format of n parameter references: "$%[1]d, $%[2]d, ..."
+func placeholders(n int) string {
+	refs := make([]string, n)
+	for i := range refs {
+		refs[i] = fmt.Sprintf("$%%[%d]d", i+1)
+	}
+	return strings.Join(refs, ", ")
+}
+
+// runSearch returns one page of matches for req, with the number of matches across all pages
+func (s *Server) runSearch(ctx context.Context, req searchRequest) (models.SearchPage, error) {
+	page := models.SearchPage{Results: []models.SearchResult{}}
+	fullText := len(req.query.Clauses) > 0
+	module := ""
+	if fullText && s.Driver != "postgres" {
+		var err error
+		if module, err = s.sqliteFTSModule(); err != nil {
+			return page, err
+		}
+	}
+	// FTS4 can't rank in SQL: relevance is computed in Go over every match, other orders
+	// score only the page
+	rankInGo := fullText && s.Driver != "postgres" && module != "fts5"
+	args := append([]interface{}{}, req.args...)
+	if fullText {
+		args[0] = s.matchExpression(req.query, module)
+	}
+
+	// Total over every page: the same conditions without cursor and limit
+	countFrom := `analyses`
+	if fullText {
+		countFrom += ` JOIN (` + s.hitsQuery(module, false) + `) h ON h.hit_id = analyses.id`
+	}
+	if err := s.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+countFrom+whereClause(req.conditions), args...).Scan(&page.Total); err != nil {
+		return page, err
+	}
+	if page.Total == 0 {
+		return page, nil
+	}
+
+	var results []models.SearchResult
+	var err error
+	switch {
+	case rankInGo && req.order.name == "relevance":
+		results, err = s.relevancePage(ctx, req, module, args)
+	case fullText:
+		results, err = s.searchPage(ctx, req, args, `
+			SELECT `+analysisColumns+`, h.score, h.snippet
+			FROM analyses
+			JOIN (`+s.hitsQuery(module, true)+`) h ON h.hit_id = analyses.id`)
+		if err == nil && rankInGo {
+			scoreResults(req.query, results)
+		}
+	default:
+		results, err = s.searchPage(ctx, req, args, `
+			SELECT `+analysisColumns+`
+			FROM analyses`)
+	}
+	if err != nil {
+		return page, err
+	}
+
+	if len(results) > req.limit {
+		results = results[:req.limit]
+		page.NextCursor = encodeCursor(req.order, results[req.limit-1])
+	}
+	page.Results = append(page.Results, results...)
+	return page, nil
+}
+
+// searchPage runs selectFrom, a SELECT of analyses (plus h.score and h.snippet when it
+// joins the hits), for the rows after req.cursor in req.order: limit+1 of them, the
+// extra one telling whether another page follows
+func (s *Server) searchPage(ctx context.Context, req searchRequest, args []interface{}, selectFrom string) ([]models.SearchResult, error) {
+	conditions := req.conditions
+	if req.cursor != nil {
+		condition, withCursor, err := req.order.after(s, *req.cursor, args)
+		if err != nil {
+			return nil, err
+		}
+		conditions, args = append(append([]string{}, conditions...), condition), withCursor
+	}
+	args = append(args, req.limit+1)
+	rows, err := s.DB.QueryContext(ctx, selectFrom+whereClause(conditions)+`
+		ORDER BY `+req.order.orderBy()+fmt.Sprintf(` LIMIT $%d`, len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if len(req.query.Clauses) > 0 {
+		return s.scanSearchResults(rows)
+	}
+	analyses, err := s.scanAnalyses(rows)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]models.SearchResult, len(analyses))
+	for i, a := range analyses {
+		results[i] = models.SearchResult{Analysis: a}
+	}
+	return results, nil
+}
+
+// relevancePage ranks every FTS4 match with BM25 from its title, summary and text,
+// then loads the limit+1 best after req.cursor with their snippets
+func (s *Server) relevancePage(ctx context.Context, req searchRequest, module string, args []interface{}) ([]models.SearchResult, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT analyses.id, COALESCE(title, ''), COALESCE(summary, ''), raw_text
+		FROM analyses
+		JOIN (`+s.hitsQuery(module, false)+`) h ON h.hit_id = analyses.id`+whereClause(req.conditions), args...)
+	if err != nil {
+		return nil, err
+	}
+	var matches []models.SearchResult
+	for rows.Next() {
+		var m models.SearchResult
+		if err := rows.Scan(&m.ID, &m.Title, &m.Summary, &m.RawText); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	scoreResults(req.query, matches)
+	matches = sortByRelevance(matches, req.order.desc, req.cursor)
+	if len(matches) > req.limit+1 {
+		matches = matches[:req.limit+1]
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]interface{}, len(matches))
+	for i, m := range matches {
+		ids[i] = m.ID
+	}
+	refs := make([]interface{}, len(ids))
+	for i := range refs {
+		refs[i] = len(args) + i + 1
+	}
+	conditions := append(append([]string{}, req.conditions...),
+		fmt.Sprintf(`analyses.id IN (`+placeholders(len(ids))+`)`, refs...))
+	rows, err = s.DB.QueryContext(ctx, `
+		SELECT `+analysisColumns+`, h.score, h.snippet
+		FROM analyses
+		JOIN (`+s.hitsQuery(module, true)+`) h ON h.hit_id = analyses.id`+whereClause(conditions),
+		append(append([]interface{}{}, args...), ids...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	loaded, err := s.scanSearchResults(rows)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]models.SearchResult, len(loaded))
+	for _, r := range loaded {
+		byID[r.ID] = r
+	}
+	results := make([]models.SearchResult, 0, len(matches))
+	for _, m := range matches {
+		if r, ok := byID[m.ID]; ok {
+			r.Score = m.Score
+			results = append(results, r)
+		}
+	}
+	return results, nil
+}
+
+// sortByRelevance orders results by score, then id, and drops those up to cursor
+func sortByRelevance(results []models.SearchResult, desc bool, cursor *pageCursor) []models.SearchResult {
+	before := func(a, b models.SearchResult) bool {
+		if a.Score != b.Score {
+			return (a.Score > b.Score) == desc
+		}
+		return (a.ID > b.ID) == desc
+	}
+	sort.SliceStable(results, func(i, j int) bool { return before(results[i], results[j]) })
+	if cursor == nil {
+		return results
+	}
+	score, err := strconv.ParseFloat(cursor.Value, 64)
+	if err != nil {
+		return nil
+	}
+	last := models.SearchResult{Analysis: models.Analysis{ID: cursor.ID}, Score: score}
+	for i, r := range results {
+		if before(last, r) {
+			return results[i:]
+		}
+	}
+	return nil
+}
+
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return ` WHERE ` + strings.Join(conditions, " AND ")
+}
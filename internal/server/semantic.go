@@ -0,0 +1,232 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+	"github.com/gbengafagbola/knowledge-extractor/internal/search"
+)
+
+const (
+	defaultSemanticResults = 10
+	maxSemanticResults     = 100
+)
+
+// SemanticSearchHandler ranks analyses by embedding similarity to ?q=
+// Scores are cosine similarities (-1..1, higher is more similar)
+func (s *Server) SemanticSearchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		http.Error(w, "missing q query param", http.StatusBadRequest)
+		return
+	}
+	limit, err := parseLimit(r, defaultSemanticResults, maxSemanticResults)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hits, err := s.vectorSearch(r.Context(), q, limit)
+	if err != nil {
+		http.Error(w, "semantic search failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	results, err := s.hydrateHits(r.Context(), hits)
+	if err != nil {
+		http.Error(w, "db query failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(results)
+}
+
+// LoadVectorIndex fills the local vector index from stored embeddings of the
+// current model. It is a no-op when pgvector serves vector queries
+func (s *Server) LoadVectorIndex(ctx context.Context) error {
+	if s.Vectors == nil {
+		return nil
+	}
+
+	rows, err := s.DB.QueryContext(ctx,
+		`SELECT analysis_id, embedding FROM analysis_embeddings WHERE model = $1`, s.Embedder.Model())
+	if err != nil {
+		return fmt.Errorf("failed to load embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var blob []byte
+		if err := rows.Scan(&id, &blob); err != nil {
+			return fmt.Errorf("failed to load embeddings: %w", err)
+		}
+		s.Vectors.Add(id, decodeVector(blob))
+	}
+	return rows.Err()
+}
+
+// vectorSearch embeds the query and returns the nearest analyses, best first
+func (s *Server) vectorSearch(ctx context.Context, q string, limit int) ([]search.Hit, error) {
+	vectors, err := s.Embedder.Embed([]string{q})
+	if err != nil {
+		return nil, fmt.Errorf("embed query: %w", err)
+	}
+	query := vectors[0]
+
+	if s.Vectors != nil {
+		return s.Vectors.Search(query, limit), nil
+	}
+
+	// pgvector: <=> is cosine distance, so similarity is 1 - distance
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT analysis_id, 1 - (embedding <=> $1::vector)
+		FROM analysis_embeddings
+		WHERE model = $2
+		ORDER BY embedding <=> $1::vector
+		LIMIT $3`,
+		vectorLiteral(query), s.Embedder.Model(), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanHits(rows)
+}
+
+// embed computes the document vector for an analysis
+// Failures are logged and yield nil: the analysis is still stored, just not searchable semantically
+func (s *Server) embed(text string) []float32 {
+	vectors, err := s.Embedder.Embed([]string{text})
+	if err != nil || len(vectors) == 0 {
+		fmt.Println("embedding failed, analysis will not be semantically searchable:", err)
+		return nil
+	}
+	return vectors[0]
+}
+
+// storeEmbedding writes the vector in the format of the embeddings column
+func (s *Server) storeEmbedding(ctx context.Context, tx *sql.Tx, analysisID string, vector []float32) error {
+	if vector == nil {
+		return nil
+	}
+
+	var value interface{} = encodeVector(vector)
+	query := `INSERT INTO analysis_embeddings (analysis_id, model, embedding) VALUES ($1,$2,$3)`
+	if s.Vectors == nil {
+		value = vectorLiteral(vector)
+		query = `INSERT INTO analysis_embeddings (analysis_id, model, embedding) VALUES ($1,$2,$3::vector)`
+	}
+
+	if _, err := tx.ExecContext(ctx, query, analysisID, s.Embedder.Model(), value); err != nil {
+		return fmt.Errorf("insert embedding: %w", err)
+	}
+	return nil
+}
+
+// hydrateHits loads the analyses behind ranked hits, keeping the hit order
+// Hits whose analysis no longer exists are skipped
+func (s *Server) hydrateHits(ctx context.Context, hits []search.Hit) ([]models.SearchResult, error) {
+	ids := make([]string, 0, len(hits))
+	for _, h := range hits {
+		ids = append(ids, h.ID)
+	}
+	byID, err := s.loadAnalyses(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.SearchResult, 0, len(hits))
+	for _, h := range hits {
+		if a, ok := byID[h.ID]; ok {
+			results = append(results, models.SearchResult{Analysis: a, Score: h.Score})
+		}
+	}
+	return results, nil
+}
+
+// loadAnalyses fetches analyses by ID
+func (s *Server) loadAnalyses(ctx context.Context, ids []string) (map[string]models.Analysis, error) {
+	byID := make(map[string]models.Analysis, len(ids))
+	if len(ids) == 0 {
+		return byID, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+	rows, err := s.DB.QueryContext(ctx,
+		`SELECT `+analysisColumns+` FROM analyses WHERE id IN (`+strings.Join(placeholders, ",")+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	analyses, err := s.scanAnalyses(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range analyses {
+		byID[a.ID] = a
+	}
+	return byID, nil
+}
+
+// parseLimit reads ?limit=, applying a default and an upper bound
+func parseLimit(r *http.Request, def, max int) (int, error) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid limit")
+	}
+	if n > max {
+		n = max
+	}
+	return n, nil
+}
+
+// encodeVector packs a vector as little-endian float32s for BLOB/BYTEA columns
+func encodeVector(vec []float32) []byte {
+	buf := make([]byte, 4*len(vec))
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+	return buf
+}
+
+func decodeVector(buf []byte) []float32 {
+	vec := make([]float32, len(buf)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return vec
+}
+
+// vectorLiteral formats a vector in pgvector's text form: [1,2,3]
+func vectorLiteral(vec []float32) string {
+	parts := make([]string, len(vec))
+	for i, v := range vec {
+		parts[i] = strconv.FormatFloat(float64(v), 'f', -1, 32)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
@@ -1,15 +1,18 @@
 package server
 
 import (
-	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
 	"github.com/gbengafagbola/knowledge-extractor/internal/llm"
 	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+	"github.com/gbengafagbola/knowledge-extractor/internal/redact"
+	"github.com/gbengafagbola/knowledge-extractor/internal/search"
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 )
@@ -17,13 +20,47 @@ import (
 // Server struct demonstrates dependency injection pattern
 // All dependencies are injected at construction time for better testability
 type Server struct {
-	DB     *sql.DB // Database connection - abstracted interface
-	LLM    llm.LLM // LLM client - interface allows for easy mocking
-	Driver string  // Database driver type for compatibility layer
+	DB       *sql.DB             // Database connection - abstracted interface
+	LLM      llm.LLM             // LLM client - interface allows for easy mocking
+	Driver   string              // Database driver type for compatibility layer
+	Embedder llm.Embedder        // Embedding provider for semantic search
+	Vectors  *search.VectorIndex // Local vector index; nil when Postgres pgvector is used
+
+	Redactor   *redact.Redactor // Removes personal data before the LLM call and storage; nil disables
+	AdminToken string           // Bearer token for re-identification and editing analyses; empty disables both
+
+	KeywordOptions   analyzer.KeywordOptions  // Local keyword extraction: stopwords, normalization, filters
+	KeywordWeighting analyzer.Weighting       // Corpus-aware keyword scoring (TF-IDF or BM25)
+	KeywordExtractor analyzer.KeyphraseMethod // Local extractor for the keywords field; empty uses frequency
+	SummaryOptions   analyzer.SummaryOptions  // Length of local extractive summaries
+
+	FieldStrategies map[string]FieldStrategy // How LLM and local output combine per field; unset fields use the LLM
+	Pipeline        *analyzer.Pipeline       // Stages of POST /analyze and their error policies
+
+	DuplicatePolicy    DuplicatePolicy // What POST /analyze does with near copies of stored analyses
+	DuplicateThreshold float64         // Estimated word-pair overlap (0-1) from which texts are near copies
 }
 
-func New(db *sql.DB, llm llm.LLM, driver string) *Server {
-	return &Server{DB: db, LLM: llm, Driver: driver}
+// New wires a server with the offline hashing embedder and a local vector index
+// Replace Embedder before Migrate/LoadVectorIndex to use another provider
+func New(db *sql.DB, llmClient llm.LLM, driver string) *Server {
+	s := &Server{
+		DB:       db,
+		LLM:      llmClient,
+		Driver:   driver,
+		Embedder: llm.NewHashingEmbedder(0),
+		Vectors:  search.NewVectorIndex(),
+
+		KeywordOptions:   analyzer.DefaultKeywordOptions(),
+		KeywordWeighting: analyzer.WeightTFIDF,
+		SummaryOptions:   analyzer.DefaultSummaryOptions(),
+		FieldStrategies:  DefaultFieldStrategies(),
+
+		DuplicatePolicy:    DuplicateStore,
+		DuplicateThreshold: analyzer.DefaultDuplicateThreshold,
+	}
+	s.Pipeline = s.DefaultPipeline()
+	return s
 }
 
 // HANDLER
@@ -34,112 +71,293 @@ func (s *Server) AnalyzeHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var input struct {
-		Text string `json:"text"`
+		Text           string `json:"text"`
+		TargetLanguage string `json:"target_language"` // Optional output language for summary/title/topics
+		Pipeline       struct {
+			Disable  []string          `json:"disable"`  // Stages to leave out of this analysis
+			Policies map[string]string `json:"policies"` // Stage -> fail, skip or degrade for this analysis
+		} `json:"pipeline"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Text == "" {
 		http.Error(w, "invalid input", http.StatusBadRequest)
 		return
 	}
 
-	// BUSINESS LOGIC: Call LLM through interface (real or mock)
-	// This demonstrates the power of interface-based design:
-	// The handler doesn't know or care which LLM implementation is used
-	summary, title, topics, sentiment, keywords, confidence, err :=
-		s.LLM.AnalyzeText(input.Text)
-	if err != nil {
-		http.Error(w, "LLM analysis failed: "+err.Error(), http.StatusInternalServerError)
+	doc := analyzer.Document{Text: input.Text, Language: analyzer.LanguageUndetermined}
+	if input.TargetLanguage != "" {
+		target, ok := analyzer.NormalizeLanguage(input.TargetLanguage)
+		if !ok {
+			http.Error(w, "unsupported target_language", http.StatusBadRequest)
+			return
+		}
+		doc.TargetLanguage = target
+	}
+	runOpts := analyzer.RunOptions{Disabled: make(map[string]bool), Policies: make(map[string]analyzer.ErrorPolicy)}
+	for _, stage := range input.Pipeline.Disable {
+		if !s.Pipeline.Has(stage) {
+			http.Error(w, "unknown pipeline stage "+stage, http.StatusBadRequest)
+			return
+		}
+		if !requestStages[stage] {
+			http.Error(w, "pipeline stage "+stage+" cannot be disabled per request", http.StatusBadRequest)
+			return
+		}
+		runOpts.Disabled[stage] = true
+	}
+	for stage, name := range input.Pipeline.Policies {
+		policy, ok := analyzer.ParseErrorPolicy(name)
+		if !s.Pipeline.Has(stage) || !ok {
+			http.Error(w, "invalid policy for pipeline stage "+stage, http.StatusBadRequest)
+			return
+		}
+		if !requestStages[stage] {
+			http.Error(w, "pipeline stage "+stage+" policy cannot be changed per request", http.StatusBadRequest)
+			return
+		}
+		runOpts.Policies[stage] = policy
+	}
+
+	// PIPELINE: normalize, redact, detect language, flag injection, deduplicate, then the
+	// local extractors and the LLM, validation against the text and enrichment
+	ctx := r.Context()
+	reports, err := s.Pipeline.Run(ctx, &doc, runOpts)
+	var duplicate *duplicateError
+	var stageErr *analyzer.StageError
+	switch {
+	case errors.As(err, &duplicate):
+		w.Header().Set("X-Duplicate-Of", duplicate.canonical)
+		if s.DuplicatePolicy == DuplicateLink {
+			byID, err := s.loadAnalyses(ctx, []string{duplicate.canonical})
+			if err != nil {
+				http.Error(w, "db query failed: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if existing, ok := byID[duplicate.canonical]; ok {
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(existing)
+				return
+			}
+		}
+		http.Error(w, duplicate.Error(), http.StatusConflict)
+		return
+	case errors.As(err, &stageErr) && stageErr.Stage == analyzer.StageNormalize:
+		http.Error(w, "invalid input: "+stageErr.Err.Error(), http.StatusBadRequest)
+		return
+	case errors.As(err, &stageErr) && stageErr.Stage == StageLLM:
+		http.Error(w, "LLM analysis failed: "+stageErr.Err.Error(), http.StatusInternalServerError)
 		return
+	case err != nil:
+		http.Error(w, "analysis failed at stage "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if doc.Analysis.DuplicateOf != "" {
+		w.Header().Set("X-Duplicate-Of", doc.Analysis.DuplicateOf)
 	}
 
-	analysis := models.Analysis{
-		ID:         uuid.NewString(),
-		RawText:    input.Text,
-		Summary:    summary,
-		Title:      title,
-		Topics:     topics,
-		Sentiment:  sentiment,
-		Keywords:   keywords,
-		Confidence: confidence,
+	analysis := doc.Analysis
+	analysis.ID = uuid.NewString()
+	analysis.RawText = doc.Text
+	analysis.Language = doc.Language
+	analysis.Pipeline = reports
+	vector := doc.Vector
+	var minhash []byte
+	if doc.Signature != nil {
+		minhash = doc.Signature.Bytes()
+	}
+	var wordCount, sentenceCount interface{}
+	var readingEase, gradeLevel *float64
+	if stats := analysis.TextStats; stats != nil {
+		wordCount, sentenceCount = stats.Words, stats.Sentences
+		readingEase, gradeLevel = stats.ReadingEase, stats.GradeLevel
 	}
 
 	// DATABASE OPERATION: Context-aware execution with proper error handling
 	// Uses parameterized queries to prevent SQL injection
-	// PostgreSQL arrays handled with pq.Array(), SQLite with comma-separated strings
+	// PostgreSQL arrays handled with pq.Array(), SQLite with JSON arrays
+	// The analysis, its entity mentions and the corpus statistics are written in one transaction
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		http.Error(w, "failed to begin db transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO analyses (id, raw_text, summary, title, topics, sentiment, keywords, confidence)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`
-	_, err = s.DB.ExecContext(context.Background(), query,
+		INSERT INTO analyses (id, raw_text, summary, title, topics, sentiment, keywords, confidence, language,
+			injection_suspected, injection_score, grounding, keyword_scores, provider, field_sources,
+			sentiment_score, sentiment_scores, text_stats, word_count, sentence_count, reading_ease, grade_level,
+			minhash, duplicate_of, terms_indexed_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,$24, CURRENT_TIMESTAMP)`
+	_, err = tx.ExecContext(ctx, query,
 		analysis.ID, analysis.RawText, analysis.Summary, analysis.Title,
 		s.formatArrayForInsert(analysis.Topics), analysis.Sentiment,
-		s.formatArrayForInsert(analysis.Keywords), analysis.Confidence,
+		s.formatArrayForInsert(analysis.Keywords), analysis.Confidence, analysis.Language,
+		analysis.InjectionSuspected, analysis.InjectionScore, jsonValue(analysis.Grounding),
+		jsonValue(analysis.KeywordScores), analysis.Provider, jsonValue(analysis.Sources),
+		analysis.SentimentScore, jsonValue(analysis.SentimentScores),
+		jsonValue(analysis.TextStats), wordCount, sentenceCount, readingEase, gradeLevel,
+		minhash, nullString(analysis.DuplicateOf),
 	)
 	if err != nil {
 		http.Error(w, "failed to insert into db: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if err := storeTerms(ctx, tx, analysis.ID, analysis.Topics, analysis.Keywords); err != nil {
+		http.Error(w, "failed to insert into db: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.storeEntities(ctx, tx, analysis.ID, analysis.Entities); err != nil {
+		http.Error(w, "failed to insert into db: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.storeEmbedding(ctx, tx, analysis.ID, vector); err != nil {
+		http.Error(w, "failed to insert into db: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.storeRedactionMap(ctx, tx, analysis.ID, doc.RedactionTokens); err != nil {
+		http.Error(w, "failed to insert into db: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if doc.Signature != nil {
+		if err := storeFingerprint(ctx, tx, analysis.ID, *doc.Signature); err != nil {
+			http.Error(w, "failed to insert into db: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Without the enrich stage the text was not counted, so it stays out of the corpus statistics
+	if doc.Terms.Terms != nil {
+		if err := s.addCorpusStats(ctx, tx, doc.Terms); err != nil {
+			http.Error(w, "failed to update corpus stats: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "failed to commit db transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if s.Vectors != nil && vector != nil {
+		s.Vectors.Add(analysis.ID, vector)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(analysis)
 }
 
+// SearchHandler finds analyses by full-text query (?q=), by topics (?topic=, repeatable, any
+// of them or with ?topic_match=all every one) and keywords (?tag=, repeatable, all required),
+// all exact but case-insensitive, or by a mentioned entity (?entity=). Filters narrow the
+// matches and alone also search: sentiment (comma-separated labels), provider, language,
+// min_confidence, created_at from/to (with tz) and text statistics (?min_words=2000&max_grade=8)
+// q matches title, summary and text: every word must occur, "quoted phrases" in order and
+// word* as a prefix; its results carry a score and a highlighted snippet
+// Results are sorted by ?sort= relevance (the default with q), date/created_at (the default
+// otherwise), confidence or title, "-" reversing, and paged by ?limit= and ?cursor= like /analyses
+// The response holds one page, the total number of matches and the next page's cursor
 func (s *Server) SearchHandler(w http.ResponseWriter, r *http.Request) {
-	topic := r.URL.Query().Get("topic")
-	if topic == "" {
-		http.Error(w, "missing topic query param", http.StatusBadRequest)
+	req, err := s.parseSearchRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	searchQuery := s.buildSearchQuery()
-	rows, err := s.DB.QueryContext(context.Background(), searchQuery, topic)
+	page, err := s.runSearch(r.Context(), req)
 	if err != nil {
 		http.Error(w, "db query failed: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(page)
+}
+
+// statsFilters maps /search query params onto text statistics columns
+// Analyses without statistics (NULL) never match; reading ease and grade are NULL for non-English text
+var statsFilters = []struct {
+	param, column, op string
+}{
+	{"min_words", "word_count", ">="},
+	{"max_words", "word_count", "<="},
+	{"min_sentences", "sentence_count", ">="},
+	{"max_sentences", "sentence_count", "<="},
+	{"min_reading_ease", "reading_ease", ">="},
+	{"max_reading_ease", "reading_ease", "<="},
+	{"min_grade", "grade_level", ">="},
+	{"max_grade", "grade_level", "<="},
+}
+
+// statsFilter is one comparison against a text statistics column
+type statsFilter struct {
+	column, op string
+	value      float64
+}
+
+// parseStatsFilters reads the statistics params present in the request
+func parseStatsFilters(r *http.Request) ([]statsFilter, error) {
+	var filters []statsFilter
+	for _, f := range statsFilters {
+		if r.URL.Query().Get(f.param) == "" {
+			continue
+		}
+		v, err := parseFloatParam(r, f.param, 0)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, statsFilter{column: f.column, op: f.op, value: v})
+	}
+	return filters, nil
+}
+
+// scanAnalyses reads rows selected with analysisColumns into models
+func (s *Server) scanAnalyses(rows *sql.Rows) ([]models.Analysis, error) {
 	var results []models.Analysis
 	for rows.Next() {
 		var a models.Analysis
-		var topicsScanner, keywordsScanner interface{}
+		dest, finish := s.analysisDest(&a)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		finish()
+		results = append(results, a)
+	}
+	return results, rows.Err()
+}
 
-		if s.Driver == "postgres" {
-			// PostgreSQL arrays need special handling with pq.StringArray
-			var topicsArray pq.StringArray
-			var keywordsArray pq.StringArray
-			topicsScanner = &topicsArray
-			keywordsScanner = &keywordsArray
-
-			err := rows.Scan(
-				&a.ID, &a.RawText, &a.Summary, &a.Title, topicsScanner,
-				&a.Sentiment, keywordsScanner, &a.Confidence, &a.CreatedAt,
-			)
-			if err != nil {
-				http.Error(w, "row scan failed: "+err.Error(), http.StatusInternalServerError)
-				return
-			}
+// analysisDest returns the scan destinations of analysisColumns for a, and a function
+// to call after a successful Scan
+// Array columns are decoded per driver (pq arrays vs comma-separated strings)
+func (s *Server) analysisDest(a *models.Analysis) ([]interface{}, func()) {
+	var topicsScanner, keywordsScanner interface{}
 
+	// PostgreSQL arrays need special handling with pq.StringArray
+	var topicsArray, keywordsArray pq.StringArray
+	if s.Driver == "postgres" {
+		topicsScanner = &topicsArray
+		keywordsScanner = &keywordsArray
+	} else {
+		topicsScanner = &sqliteStringArray{&a.Topics}
+		keywordsScanner = &sqliteStringArray{&a.Keywords}
+	}
+
+	dest := []interface{}{
+		&a.ID, &a.RawText, &a.Summary, &a.Title, topicsScanner,
+		&a.Sentiment, keywordsScanner, &a.Confidence, &a.CreatedAt, &a.Language,
+		&a.InjectionSuspected, &a.InjectionScore, jsonColumn{&a.Grounding}, jsonColumn{&a.KeywordScores},
+		&a.Provider, jsonColumn{&a.Sources}, &a.SentimentScore, jsonColumn{&a.SentimentScores},
+		jsonColumn{&a.TextStats}, &a.DuplicateOf, &a.UpdatedAt,
+	}
+	return dest, func() {
+		if s.Driver == "postgres" {
 			// Convert pq.StringArray back to []string
 			a.Topics = []string(topicsArray)
 			a.Keywords = []string(keywordsArray)
-		} else {
-			topicsScanner = &sqliteStringArray{&a.Topics}
-			keywordsScanner = &sqliteStringArray{&a.Keywords}
-
-			err := rows.Scan(
-				&a.ID, &a.RawText, &a.Summary, &a.Title, topicsScanner,
-				&a.Sentiment, keywordsScanner, &a.Confidence, &a.CreatedAt,
-			)
-			if err != nil {
-				http.Error(w, "row scan failed: "+err.Error(), http.StatusInternalServerError)
-				return
-			}
 		}
-
-		results = append(results, a)
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(results)
 }
 
 // helpers
@@ -148,11 +366,15 @@ func (s *Server) formatArrayForInsert(arr []string) interface{} {
 		// Use pq.Array for proper PostgreSQL array handling
 		return pq.Array(arr)
 	}
-	// SQLite - store as comma-separated string
-	return joinStrings(arr, ",")
+	// SQLite - store as a JSON array
+	if arr == nil {
+		arr = []string{}
+	}
+	return jsonValue(arr)
 }
 
-// sqliteStringArray handles scanning comma-separated strings for SQLite
+// sqliteStringArray scans SQLite array columns: JSON arrays, or the comma-separated
+// strings (and Postgres array literals) of rows written before JSON was used
 type sqliteStringArray struct {
 	arr *[]string
 }
@@ -177,8 +399,22 @@ func (s *sqliteStringArray) Scan(value interface{}) error {
 		*s.arr = []string{}
 		return nil
 	}
+	if strings.HasPrefix(str, "[") {
+		var arr []string
+		if err := json.Unmarshal([]byte(str), &arr); err == nil {
+			*s.arr = arr
+			return nil
+		}
+	}
+	if strings.HasPrefix(str, "{") && strings.HasSuffix(str, "}") {
+		var arr pq.StringArray
+		if err := arr.Scan(str); err == nil {
+			*s.arr = []string(arr)
+			return nil
+		}
+	}
 
-	// Split comma-separated string and trim spaces
+	// Legacy: split comma-separated string and trim spaces
 	parts := strings.Split(str, ",")
 	result := make([]string, 0, len(parts))
 	for _, part := range parts {
@@ -191,25 +427,47 @@ func (s *sqliteStringArray) Scan(value interface{}) error {
 	return nil
 }
 
-func (s *Server) buildSearchQuery() string {
-	if s.Driver == "postgres" {
-		return `SELECT id, raw_text, summary, title, topics, sentiment, keywords, confidence, created_at
-		 FROM analyses
-		 WHERE $1 = ANY(topics) OR $1 = ANY(keywords)`
-	}
-	// SQLite - use LIKE with comma-separated strings
-	return `SELECT id, raw_text, summary, title, topics, sentiment, keywords, confidence, created_at
-		 FROM analyses
-		 WHERE topics LIKE '%' || $1 || '%' OR keywords LIKE '%' || $1 || '%'`
+// jsonColumn scans a JSON/JSONB column into dest; NULL leaves dest untouched
+type jsonColumn struct {
+	dest interface{}
 }
 
-func joinStrings(arr []string, sep string) string {
-	out := ""
-	for i, v := range arr {
-		if i > 0 {
-			out += sep
-		}
-		out += v
+func (j jsonColumn) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), j.dest)
+	case []byte:
+		return json.Unmarshal(v, j.dest)
+	default:
+		return fmt.Errorf("cannot scan %T into jsonColumn", value)
 	}
-	return out
 }
+
+// jsonValue encodes v for a JSON/JSONB column (text on SQLite)
+func jsonValue(v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return string(b)
+}
+
+// analysisColumns is the column list every analyses SELECT uses, in scanAnalyses order
+// Columns added after the first release are COALESCEd (or scanned NULL-tolerantly)
+// so legacy rows scan cleanly
+const analysisColumns = `id, raw_text, summary, title, topics, sentiment, keywords, confidence, created_at,
+	COALESCE(language, ''), COALESCE(injection_suspected, false), COALESCE(injection_score, 0),
+	grounding, keyword_scores, COALESCE(provider, ''), field_sources,
+	COALESCE(sentiment_score, 0), sentiment_scores, text_stats, COALESCE(duplicate_of, ''), updated_at`
+
+// entityCondition matches analyses mentioning an entity by canonical name (case-insensitive);
+// it is a format of the parameter reference, e.g. fmt.Sprintf(entityCondition, 1) for $1
+// The same SQL works on both drivers
+const entityCondition = `id IN (
+			SELECT m.analysis_id
+			FROM entity_mentions m
+			JOIN entities e ON e.id = m.entity_id
+			WHERE lower(e.name) = lower($%[1]d)
+		 )`
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
+	"github.com/lib/pq"
+)
+
+// Kinds of analysis_terms rows
+const (
+	termTopic   = "topic"
+	termKeyword = "keyword"
+)
+
+// termKey is the form topics and keywords are matched by: case-folded with
+// whitespace collapsed, so "Machine  Learning" and "machine learning" are one term
+func termKey(term string) string {
+	return strings.Join(strings.Fields(analyzer.Fold(term)), " ")
+}
+
+// storeTerms indexes an analysis' topics and keywords, one analysis_terms row each,
+// keeping their order; the analysis must not have terms stored yet
+func storeTerms(ctx context.Context, tx *sql.Tx, analysisID string, topics, keywords []string) error {
+	args := []interface{}{analysisID}
+	var values []string
+	for _, list := range []struct {
+		kind  string
+		terms []string
+	}{{termTopic, topics}, {termKeyword, keywords}} {
+		for i, t := range list.terms {
+			args = append(args, list.kind, i, t, termKey(t))
+			n := len(args)
+			values = append(values, fmt.Sprintf("($1, $%d, $%d, $%d, $%d)", n-3, n-2, n-1, n))
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO analysis_terms (analysis_id, kind, position, term, term_key)
+		VALUES `+strings.Join(values, ", "), args...)
+	if err != nil {
+		return fmt.Errorf("insert analysis terms: %w", err)
+	}
+	return nil
+}
+
+// replaceTopics swaps the indexed topics of an analysis for topics
+func replaceTopics(ctx context.Context, tx *sql.Tx, analysisID string, topics []string) error {
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM analysis_terms WHERE analysis_id = $1 AND kind = $2`, analysisID, termTopic); err != nil {
+		return fmt.Errorf("delete analysis terms: %w", err)
+	}
+	return storeTerms(ctx, tx, analysisID, topics, nil)
+}
+
+// migrateTerms indexes the topics and keywords of analyses stored before analysis_terms
+// existed. On SQLite it also rewrites their comma-separated columns as JSON arrays,
+// which keep topics containing commas intact
+// terms_indexed_at marks indexed analyses, so each is visited once even when it has no terms
+func (s *Server) migrateTerms() error {
+	rows, err := s.DB.Query(`
+		SELECT id, topics, keywords FROM analyses
+		WHERE terms_indexed_at IS NULL
+			AND NOT EXISTS (SELECT 1 FROM analysis_terms t WHERE t.analysis_id = analyses.id)`)
+	if err != nil {
+		return err
+	}
+	type legacyTerms struct {
+		id               string
+		topics, keywords []string
+	}
+	var legacy []legacyTerms
+	for rows.Next() {
+		var l legacyTerms
+		var topicsScanner, keywordsScanner interface{} = &sqliteStringArray{&l.topics}, &sqliteStringArray{&l.keywords}
+		if s.Driver == "postgres" {
+			topicsScanner, keywordsScanner = (*pq.StringArray)(&l.topics), (*pq.StringArray)(&l.keywords)
+		}
+		if err := rows.Scan(&l.id, topicsScanner, keywordsScanner); err != nil {
+			rows.Close()
+			return err
+		}
+		legacy = append(legacy, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	for _, l := range legacy {
+		tx, err := s.DB.Begin()
+		if err != nil {
+			return err
+		}
+		if s.Driver != "postgres" {
+			_, err := tx.Exec(`UPDATE analyses SET topics = $1, keywords = $2 WHERE id = $3`,
+				s.formatArrayForInsert(l.topics), s.formatArrayForInsert(l.keywords), l.id)
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+		if err := storeTerms(ctx, tx, l.id, l.topics, l.keywords); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE analyses SET terms_indexed_at = CURRENT_TIMESTAMP WHERE id = $1`, l.id); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	// Analyses indexed before the marker existed
+	_, err = s.DB.Exec(`UPDATE analyses SET terms_indexed_at = CURRENT_TIMESTAMP WHERE terms_indexed_at IS NULL`)
+	return err
+}
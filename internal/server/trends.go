@@ -0,0 +1,345 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gbengafagbola/knowledge-extractor/internal/analyzer"
+	"github.com/gbengafagbola/knowledge-extractor/internal/models"
+	"github.com/lib/pq"
+)
+
+const (
+	defaultTrendTerms = 10
+	maxTrendTerms     = 100
+	maxTrendBuckets   = 2000
+)
+
+// Trend intervals and how far back each looks by default
+var trendSpans = map[string]func(to time.Time) time.Time{
+	"hour": func(to time.Time) time.Time { return to.Add(-24 * time.Hour) },
+	"day":  func(to time.Time) time.Time { return to.AddDate(0, 0, -30) },
+	"week": func(to time.Time) time.Time { return to.AddDate(0, 0, -7*12) },
+}
+
+// trendRow is the part of an analysis trends are computed from
+type trendRow struct {
+	createdAt time.Time
+	terms     []string
+	sentiment string
+	score     float64
+}
+
+// TrendsHandler buckets analyses by creation time (GET /trends)
+// Params: interval=hour|day|week (default day), tz=IANA zone (default UTC),
+// from/to as RFC 3339 or YYYY-MM-DD in tz (default: the last 24 hours, 30 days or 12 weeks),
+// field=topics|keywords|both (default topics), limit=terms per bucket and per rising/falling list
+// Rising and falling terms compare [from, to) with the period of equal length before it
+func (s *Server) TrendsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+
+	interval := q.Get("interval")
+	if interval == "" {
+		interval = "day"
+	}
+	span, ok := trendSpans[interval]
+	if !ok {
+		http.Error(w, "invalid interval (hour, day or week)", http.StatusBadRequest)
+		return
+	}
+	tz := q.Get("tz")
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		http.Error(w, "invalid tz", http.StatusBadRequest)
+		return
+	}
+	field := q.Get("field")
+	if field == "" {
+		field = "topics"
+	}
+	if field != "topics" && field != "keywords" && field != "both" {
+		http.Error(w, "invalid field (topics, keywords or both)", http.StatusBadRequest)
+		return
+	}
+	limit, err := parseLimit(r, defaultTrendTerms, maxTrendTerms)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	to := time.Now().In(loc)
+	if v := q.Get("to"); v != "" {
+		if to, err = parseTrendTime(v, loc); err != nil {
+			http.Error(w, "invalid to", http.StatusBadRequest)
+			return
+		}
+	}
+	from := span(to)
+	if v := q.Get("from"); v != "" {
+		if from, err = parseTrendTime(v, loc); err != nil {
+			http.Error(w, "invalid from", http.StatusBadRequest)
+			return
+		}
+	}
+	from = truncateBucket(from, interval)
+	if !from.Before(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+	var starts []time.Time
+	for t := from; t.Before(to); t = nextBucket(t, interval) {
+		if len(starts) == maxTrendBuckets {
+			http.Error(w, fmt.Sprintf("range spans more than %d buckets", maxTrendBuckets), http.StatusBadRequest)
+			return
+		}
+		starts = append(starts, t)
+	}
+
+	previousFrom := from.Add(-to.Sub(from))
+	rows, err := s.loadTrendRows(r.Context(), previousFrom, to, field)
+	if err != nil {
+		http.Error(w, "db query failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	trends := models.Trends{
+		Interval: interval,
+		TimeZone: loc.String(),
+		Field:    field,
+		From:     from,
+		To:       to,
+		Buckets:  make([]models.TrendBucket, len(starts)),
+	}
+	bucketTerms := make([]termTally, len(starts))
+	scoreSums := make([]float64, len(starts))
+	current, previous := newTermTally(), newTermTally()
+	for i, start := range starts {
+		trends.Buckets[i].Start = start
+		bucketTerms[i] = newTermTally()
+	}
+	for _, row := range rows {
+		if row.createdAt.Before(from) {
+			previous.add(row.terms)
+			continue
+		}
+		current.add(row.terms)
+		// Buckets are contiguous and ordered, so the last start not after the row is its bucket
+		i := sort.Search(len(starts), func(i int) bool { return starts[i].After(row.createdAt) }) - 1
+		if i < 0 {
+			continue
+		}
+		b := &trends.Buckets[i]
+		b.Documents++
+		switch row.sentiment {
+		case analyzer.SentimentPositive:
+			b.Sentiment.Positive++
+		case analyzer.SentimentNeutral:
+			b.Sentiment.Neutral++
+		case analyzer.SentimentNegative:
+			b.Sentiment.Negative++
+		default:
+			b.Sentiment.Unknown++
+		}
+		scoreSums[i] += row.score
+		bucketTerms[i].add(row.terms)
+	}
+	for i := range trends.Buckets {
+		b := &trends.Buckets[i]
+		if b.Documents > 0 {
+			b.AvgSentimentScore = math.Round(scoreSums[i]/float64(b.Documents)*1000) / 1000
+		}
+		b.Terms = bucketTerms[i].top(limit)
+	}
+	trends.Rising, trends.Falling = termChanges(current, previous, limit)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(trends)
+}
+
+// loadTrendRows reads the analyses created in [from, to), oldest first
+func (s *Server) loadTrendRows(ctx context.Context, from, to time.Time, field string) ([]trendRow, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT created_at, topics, keywords, COALESCE(sentiment, ''), COALESCE(sentiment_score, 0)
+		FROM analyses
+		WHERE created_at >= $1 AND created_at < $2
+		ORDER BY created_at`,
+		s.timeParam(from), s.timeParam(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []trendRow
+	for rows.Next() {
+		var row trendRow
+		var topics, keywords []string
+		var topicsScanner, keywordsScanner interface{} = &sqliteStringArray{&topics}, &sqliteStringArray{&keywords}
+		if s.Driver == "postgres" {
+			topicsScanner, keywordsScanner = (*pq.StringArray)(&topics), (*pq.StringArray)(&keywords)
+		}
+		if err := rows.Scan(&row.createdAt, topicsScanner, keywordsScanner, &row.sentiment, &row.score); err != nil {
+			return nil, err
+		}
+		switch field {
+		case "topics":
+			row.terms = topics
+		case "keywords":
+			row.terms = keywords
+		default:
+			row.terms = append(topics, keywords...)
+		}
+		row.sentiment = strings.ToLower(row.sentiment)
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// timeParam binds a time for comparison with created_at: SQLite stores
+// CURRENT_TIMESTAMP as "YYYY-MM-DD HH:MM:SS" text in UTC, which only compares
+// correctly against text in the same format
+func (s *Server) timeParam(t time.Time) interface{} {
+	if s.Driver == "postgres" {
+		return t
+	}
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// parseTrendTime reads RFC 3339 timestamps or dates, which start at midnight in loc
+func parseTrendTime(v string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.In(loc), nil
+	}
+	return time.ParseInLocation("2006-01-02", v, loc)
+}
+
+// truncateBucket returns the start of the bucket containing t, in t's location;
+// weeks start on Monday
+func truncateBucket(t time.Time, interval string) time.Time {
+	y, m, d := t.Date()
+	switch interval {
+	case "hour":
+		return time.Date(y, m, d, t.Hour(), 0, 0, 0, t.Location())
+	case "week":
+		d -= (int(t.Weekday()) + 6) % 7
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// nextBucket steps by calendar days so buckets stay aligned to local midnight across DST changes
+func nextBucket(t time.Time, interval string) time.Time {
+	switch interval {
+	case "hour":
+		return t.Add(time.Hour)
+	case "week":
+		return t.AddDate(0, 0, 7)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// termTally counts the analyses listing each term, case-insensitively,
+// keeping the first spelling seen for display
+type termTally struct {
+	counts  map[string]int
+	display map[string]string
+}
+
+func newTermTally() termTally {
+	return termTally{counts: make(map[string]int), display: make(map[string]string)}
+}
+
+// add counts one analysis' terms; a term listed twice by the same analysis counts once
+func (t termTally) add(terms []string) {
+	seen := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		key := strings.ToLower(term)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		t.counts[key]++
+		if _, ok := t.display[key]; !ok {
+			t.display[key] = term
+		}
+	}
+}
+
+// top returns the n most frequent terms; ties are broken alphabetically
+func (t termTally) top(n int) []models.TermCount {
+	out := make([]models.TermCount, 0, len(t.counts))
+	for key, count := range t.counts {
+		out = append(out, models.TermCount{Term: t.display[key], Count: count})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return strings.ToLower(out[i].Term) < strings.ToLower(out[j].Term)
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// termChanges ranks the terms whose counts moved most between previous and current,
+// by absolute change and then by relative growth
+func termChanges(current, previous termTally, n int) (rising, falling []models.TermChange) {
+	rising, falling = []models.TermChange{}, []models.TermChange{}
+	keys := make(map[string]bool)
+	for key := range current.counts {
+		keys[key] = true
+	}
+	for key := range previous.counts {
+		keys[key] = true
+	}
+	for key := range keys {
+		cur, prev := current.counts[key], previous.counts[key]
+		display := current.display[key]
+		if display == "" {
+			display = previous.display[key]
+		}
+		c := models.TermChange{Term: display, Previous: prev, Current: cur, Change: cur - prev}
+		c.Growth = math.Round(float64(c.Change)/math.Max(float64(prev), 1)*1000) / 1000
+		switch {
+		case c.Change > 0:
+			rising = append(rising, c)
+		case c.Change < 0:
+			falling = append(falling, c)
+		}
+	}
+	order := func(list []models.TermChange, sign int) {
+		sort.Slice(list, func(i, j int) bool {
+			a, b := list[i], list[j]
+			if a.Change != b.Change {
+				return sign*a.Change > sign*b.Change
+			}
+			if a.Growth != b.Growth {
+				return float64(sign)*a.Growth > float64(sign)*b.Growth
+			}
+			return strings.ToLower(a.Term) < strings.ToLower(b.Term)
+		})
+	}
+	order(rising, 1)
+	order(falling, -1)
+	if len(rising) > n {
+		rising = rising[:n]
+	}
+	if len(falling) > n {
+		falling = falling[:n]
+	}
+	return rising, falling
+}
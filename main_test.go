@@ -5,10 +5,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gbengafagbola/knowledge-extractor/internal/llm"
 	"github.com/gbengafagbola/knowledge-extractor/internal/server"
+	"github.com/lib/pq"
 )
 
 // newMockServer sets up a test server with sqlmock + a mock LLM
@@ -25,9 +27,31 @@ func newMockServer(t *testing.T) (*server.Server, sqlmock.Sqlmock) {
 func TestAnalyzeHandler(t *testing.T) {
 	s, mock := newMockServer(t)
 
+	// Stored fingerprints are checked for near-duplicates before the LLM call
+	mock.ExpectQuery("FROM analysis_fingerprints").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "minhash", "duplicate_of", "created_at"}))
+
+	// Corpus statistics are read to score keywords before the insert
+	mock.ExpectQuery("SELECT documents, tokens FROM corpus_stats").
+		WillReturnRows(sqlmock.NewRows([]string{"documents", "tokens"}))
+	mock.ExpectQuery("SELECT term, documents FROM term_stats").
+		WillReturnRows(sqlmock.NewRows([]string{"term", "documents"}))
+
 	// Expect insert query since AnalyzeHandler writes results to DB
+	mock.ExpectBegin()
 	mock.ExpectExec("INSERT INTO analyses").
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO analysis_terms").
+		WillReturnResult(sqlmock.NewResult(1, 5))
+	mock.ExpectExec("INSERT INTO analysis_embeddings").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO analysis_fingerprints").
+		WillReturnResult(sqlmock.NewResult(1, 16))
+	mock.ExpectExec("INSERT INTO corpus_stats").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO term_stats").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	// Simulate POST /analyze with some text
 	body := `{"text":"Go is fast"}`
@@ -52,15 +76,24 @@ func TestSearchHandler(t *testing.T) {
 
 	// Mock a row that matches search
 	rows := sqlmock.NewRows([]string{
-		"id", "raw_text", "summary", "title", "topics", "sentiment", "keywords", "confidence", "created_at",
+		"id", "raw_text", "summary", "title", "topics", "sentiment", "keywords", "confidence", "created_at", "language",
+		"injection_suspected", "injection_score", "grounding", "keyword_scores",
+		"provider", "field_sources", "sentiment_score", "sentiment_scores", "text_stats",
+		"duplicate_of", "updated_at",
 	}).AddRow(
 		"1", "raw", "sum", "title",
-		[]string{"go"}, "neutral", []string{"fast"},
-		0.9, "2024-01-01T00:00:00Z",
+		pq.Array([]string{"go"}), "neutral", pq.Array([]string{"fast"}),
+		0.9, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "en",
+		false, 0.02, nil, nil,
+		"mock", nil, 0.0, nil, nil,
+		"", nil,
 	)
 
-	mock.ExpectQuery("SELECT id, raw_text").
+	mock.ExpectQuery("SELECT COUNT").
 		WithArgs("go").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT id, raw_text").
+		WithArgs("go", 21).
 		WillReturnRows(rows)
 
 	req := httptest.NewRequest(http.MethodGet, "/search?topic=go", nil)
@@ -76,3 +109,47 @@ func TestSearchHandler(t *testing.T) {
 		t.Errorf("unmet db expectations: %v", err)
 	}
 }
+
+func TestHybridSearchHandlerQuotesLexemes(t *testing.T) {
+	s, mock := newMockServer(t)
+
+	// Query terms keep apostrophes, which must not reach to_tsquery as syntax
+	mock.ExpectQuery("ts_rank_cd").
+		WithArgs(`'o''brien' | 'smith'`, 50).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "ts_rank_cd"}))
+
+	req := httptest.NewRequest(http.MethodGet, "/search/hybrid?q=o%27brien+smith&vector_weight=0", nil)
+	w := httptest.NewRecorder()
+
+	s.HybridSearchHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet db expectations: %v", err)
+	}
+}
+
+func TestAskHandlerEscapesLikeWildcards(t *testing.T) {
+	s, mock := newMockServer(t)
+
+	// "_" is kept inside tokens, and must match only itself in the candidate pre-filter
+	mock.ExpectQuery("LIKE").
+		WithArgs(`%snake\_case%`, 200).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	req := httptest.NewRequest(http.MethodPost, "/ask", bytes.NewBufferString(`{"question":"snake_case"}`))
+	w := httptest.NewRecorder()
+
+	s.AskHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet db expectations: %v", err)
+	}
+}